@@ -0,0 +1,225 @@
+// Package audit 审计日志，记录修改数据的请求: 谁、在什么时间、修改了什么、结果如何
+//
+// 记录在请求结束后放入队列，由后台写入 Sink，不会阻塞请求，队列已满时丢弃并计数
+package audit
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/auth/basic"
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// Record 一条审计记录
+type Record struct {
+	Time      time.Time `json:"time"`
+	RequestID string    `json:"request_id,omitempty"`
+	Principal string    `json:"principal"`
+	IP        string    `json:"ip"`
+	Method    string    `json:"method"`
+	Route     string    `json:"route"`
+	Resources []string  `json:"resources,omitempty"`
+	Status    int       `json:"status"`
+	Success   bool      `json:"success"`
+}
+
+// Sink 保存审计记录
+type Sink interface {
+	Write(record *Record) error
+}
+
+// SinkFunc 使用函数作为 Sink，如写入数据库或者消息队列
+type SinkFunc func(record *Record) error
+
+// Write 实现 Sink
+func (f SinkFunc) Write(record *Record) error {
+	return f(record)
+}
+
+// Config 配置
+type Config struct {
+	// Sink 保存审计记录，必填
+	Sink Sink
+
+	// Methods 需要审计的请求方法，默认 POST, PUT, PATCH, DELETE
+	Methods []string
+
+	// Principal 获取操作者，默认为 DefaultPrincipal
+	Principal func(ctx zeroapi.Context) string
+
+	// Route 获取匹配的路由，如 "/user/:id"，默认为请求路径
+	Route func(ctx zeroapi.Context) string
+
+	// Resources 获取被操作的资源 id，如 url 参数或者请求体中的 id
+	Resources func(ctx zeroapi.Context) []string
+
+	// QueueSize 等待写入的记录的最大个数，默认 1024
+	QueueSize int
+
+	// OnError 写入失败或者丢弃记录时调用，默认忽略
+	OnError func(record *Record, err error)
+}
+
+// Auditor 审计
+type Auditor struct {
+	config  *Config
+	methods map[string]struct{}
+
+	lock    sync.RWMutex
+	closed  bool
+	queue   chan *Record
+	dropped atomic.Uint64
+	done    chan struct{}
+}
+
+// New 创建审计中间件，Sink 为 nil 时 panic
+func New(config *Config) *Auditor {
+	if config == nil || config.Sink == nil {
+		panic("audit sink cant be nil")
+	}
+
+	c := *config
+
+	if len(c.Methods) == 0 {
+		c.Methods = []string{http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+	}
+
+	if c.Principal == nil {
+		c.Principal = DefaultPrincipal
+	}
+
+	if c.Route == nil {
+		c.Route = func(ctx zeroapi.Context) string { return ctx.Path() }
+	}
+
+	if c.QueueSize <= 0 {
+		c.QueueSize = 1024
+	}
+
+	a := &Auditor{
+		config:  &c,
+		methods: make(map[string]struct{}, len(c.Methods)),
+		queue:   make(chan *Record, c.QueueSize),
+		done:    make(chan struct{}),
+	}
+
+	for _, method := range c.Methods {
+		a.methods[strings.ToUpper(method)] = struct{}{}
+	}
+
+	go a.run()
+
+	return a
+}
+
+// Handle 中间件，请求结束时记录，放在认证中间件之前时，认证失败的请求也会被记录
+func (a *Auditor) Handle(ctx zeroapi.Context) {
+	if _, ok := a.methods[ctx.Method()]; !ok {
+		return
+	}
+
+	start := time.Now()
+
+	ctx.AppendEnd(func() error {
+		status := ctx.HTTPCode()
+
+		record := &Record{
+			Time:      start,
+			RequestID: requestid.Get(ctx),
+			Principal: a.config.Principal(ctx),
+			IP:        realip.IP(ctx),
+			Method:    ctx.Method(),
+			Route:     a.config.Route(ctx),
+			Status:    status,
+			Success:   status < http.StatusBadRequest,
+		}
+
+		if a.config.Resources != nil {
+			record.Resources = a.config.Resources(ctx)
+		}
+
+		a.push(record)
+		return nil
+	})
+}
+
+// push 放入队列，队列已满或者已关闭时丢弃
+func (a *Auditor) push(record *Record) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+
+	if a.closed {
+		a.drop(record)
+		return
+	}
+
+	select {
+	case a.queue <- record:
+	default:
+		a.drop(record)
+	}
+}
+
+func (a *Auditor) drop(record *Record) {
+	a.dropped.Add(1)
+	if a.config.OnError != nil {
+		a.config.OnError(record, fmt.Errorf("audit record dropped, total: %d", a.dropped.Load()))
+	}
+}
+
+// Dropped 因队列已满而丢弃的记录个数
+func (a *Auditor) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Close 停止接收记录，写入队列中的所有记录后返回，Sink 实现了 Close 时关闭 Sink
+func (a *Auditor) Close() error {
+	a.lock.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.queue)
+	}
+	a.lock.Unlock()
+
+	<-a.done
+
+	if closer, ok := a.config.Sink.(interface{ Close() error }); ok {
+		return closer.Close()
+	}
+
+	return nil
+}
+
+// run 后台写入记录
+func (a *Auditor) run() {
+	defer close(a.done)
+
+	for record := range a.queue {
+		if err := a.config.Sink.Write(record); err != nil && a.config.OnError != nil {
+			a.config.OnError(record, err)
+		}
+	}
+}
+
+// DefaultPrincipal 默认的获取操作者
+//
+// 依次使用 basic 认证的账号、jwt 中的 sub，均不存在时返回空字符串
+func DefaultPrincipal(ctx zeroapi.Context) string {
+	if account, ok := ctx.Value(basic.Key).(string); ok && account != "" {
+		return account
+	}
+
+	if sub := ctx.Value("sub"); sub != nil {
+		return fmt.Sprint(sub)
+	}
+
+	return ""
+}
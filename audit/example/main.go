@@ -0,0 +1,56 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamaudit "github.com/zerogo-hub/zero-api-middleware/audit"
+	zambasic "github.com/zerogo-hub/zero-api-middleware/auth/basic"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", pid, pid)
+}
+
+func main() {
+	a := app.New()
+
+	// 密钥与审计文件分开保存，校验: zamaudit.Verify(file, key)
+	sink, err := zamaudit.NewFileSink("audit.log", []byte(os.Getenv("AUDIT_KEY")))
+	if err != nil {
+		a.Logger().Errorf("open audit file failed, err: %s", err.Error())
+		return
+	}
+
+	// 也可以使用 zamaudit.SinkFunc 写入数据库或者消息队列
+	auditor := zamaudit.New(&zamaudit.Config{
+		Sink: sink,
+		Resources: func(ctx zeroapi.Context) []string {
+			// /user/123 -> 123
+			return []string{strings.TrimPrefix(ctx.Path(), "/user/")}
+		},
+		OnError: func(record *zamaudit.Record, err error) {
+			a.Logger().Errorf("audit failed, err: %s", err.Error())
+		},
+	})
+	defer auditor.Close()
+
+	// 放在认证之前，认证失败的请求也会被记录
+	a.Use(auditor.Handle)
+	a.Use(zambasic.New(nil, map[string]string{"admin": "123456"}))
+
+	// curl -X POST -u admin:123456 http://127.0.0.1:8877/user/123
+	// audit.log:
+	// {"time":"...","principal":"admin","ip":"127.0.0.1","method":"POST","route":"/user/123","resources":["123"],"status":200,"success":true,"prev":"...","mac":"..."}
+	a.Post("/user/123", helloworldHandle)
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
@@ -0,0 +1,176 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+)
+
+var (
+	// ErrBrokenChain 审计文件被篡改，hash 链不连续
+	ErrBrokenChain = errors.New("audit: broken hash chain")
+
+	// ErrEmptyKey 没有提供计算 HMAC 的密钥
+	ErrEmptyKey = errors.New("audit: empty hmac key")
+)
+
+// macField 每一行末尾的 mac 字段
+var macField = []byte(`,"mac":"`)
+
+// chained 写入文件的记录，prev 为上一行的 mac
+type chained struct {
+	*Record
+	Prev string `json:"prev"`
+}
+
+// FileSink 将记录以 JSON 行追加到文件，每一行包含上一行的 mac 与本行的 mac，形成 hash 链
+//
+// mac 为使用密钥计算的 HMAC-SHA256，没有密钥时无法重新计算整条链，修改或者删除任意一行都会使之后的 hash 链断开，使用 Verify 检查
+// 删除末尾的若干行不会使 hash 链断开，需要时定期将 Head 保存到文件以外，如数据库或者其它服务器
+type FileSink struct {
+	lock sync.Mutex
+	file *os.File
+	key  []byte
+	prev string
+
+	// Sync 每次写入后是否调用 fsync
+	Sync bool
+}
+
+// NewFileSink 打开或者创建审计文件，已存在时检查 hash 链，并从最后一行继续
+//
+// key 为计算 HMAC 的密钥，不能为空，应与审计文件分开保存
+func NewFileSink(path string, key []byte) (*FileSink, error) {
+	if len(key) == 0 {
+		return nil, ErrEmptyKey
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, err
+	}
+
+	key = append([]byte(nil), key...)
+
+	prev, _, err := verify(file, key)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	return &FileSink{file: file, key: key, prev: prev}, nil
+}
+
+// Write 实现 Sink
+func (s *FileSink) Write(record *Record) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	content, err := json.Marshal(&chained{Record: record, Prev: s.prev})
+	if err != nil {
+		return err
+	}
+
+	mac := sum(s.key, content)
+
+	line := make([]byte, 0, len(content)+len(macField)+len(mac)+3)
+	line = append(line, content[:len(content)-1]...)
+	line = append(line, macField...)
+	line = append(line, mac...)
+	line = append(line, '"', '}', '\n')
+
+	if _, err := s.file.Write(line); err != nil {
+		return err
+	}
+
+	if s.Sync {
+		if err := s.file.Sync(); err != nil {
+			return err
+		}
+	}
+
+	s.prev = mac
+	return nil
+}
+
+// Head 最后一行的 mac，文件为空时返回空字符串
+func (s *FileSink) Head() string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.prev
+}
+
+// Close 关闭文件
+func (s *FileSink) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.file.Close()
+}
+
+// Verify 使用密钥检查审计文件的 hash 链，返回记录的条数，hash 链断开时返回 ErrBrokenChain 与所在行
+func Verify(r io.Reader, key []byte) (int, error) {
+	if len(key) == 0 {
+		return 0, ErrEmptyKey
+	}
+
+	_, n, err := verify(r, key)
+	return n, err
+}
+
+// verify 返回最后一行的 mac 与记录的条数
+func verify(r io.Reader, key []byte) (string, int, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+
+	prev := ""
+	n := 0
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		n++
+
+		i := bytes.LastIndex(line, macField)
+		if i < 0 || !bytes.HasSuffix(line, []byte(`"}`)) {
+			return "", n, fmt.Errorf("%w, line: %d", ErrBrokenChain, n)
+		}
+
+		mac := string(line[i+len(macField) : len(line)-2])
+		content := append(line[:i:i], '}')
+
+		if !hmac.Equal([]byte(sum(key, content)), []byte(mac)) {
+			return "", n, fmt.Errorf("%w, line: %d", ErrBrokenChain, n)
+		}
+
+		c := &chained{}
+		if err := json.Unmarshal(content, c); err != nil || c.Prev != prev {
+			return "", n, fmt.Errorf("%w, line: %d", ErrBrokenChain, n)
+		}
+
+		prev = mac
+	}
+
+	if err := scanner.Err(); err != nil {
+		return "", n, err
+	}
+
+	return prev, n, nil
+}
+
+// sum 计算 content 的 HMAC-SHA256
+func sum(key, content []byte) string {
+	h := hmac.New(sha256.New, key)
+	h.Write(content)
+	return hex.EncodeToString(h.Sum(nil))
+}
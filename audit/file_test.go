@@ -0,0 +1,167 @@
+package audit
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testKey = []byte("audit-test-key")
+
+// writeRecords 创建审计文件并写入 n 条记录，返回文件路径
+func writeRecords(t *testing.T, n int) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+
+	sink, err := NewFileSink(path, testKey)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+	defer sink.Close()
+
+	for i := 0; i < n; i++ {
+		record := &Record{
+			Time:      time.Unix(1600000000+int64(i), 0).UTC(),
+			Principal: "admin",
+			IP:        "127.0.0.1",
+			Method:    "POST",
+			Route:     "/user/:id",
+			Status:    200,
+			Success:   true,
+		}
+
+		if err := sink.Write(record); err != nil {
+			t.Fatalf("Write() error = %v", err)
+		}
+	}
+
+	return path
+}
+
+func readLines(t *testing.T, path string) []string {
+	t.Helper()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	return strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+}
+
+func verifyLines(lines []string, key []byte) (int, error) {
+	return Verify(strings.NewReader(strings.Join(lines, "\n")+"\n"), key)
+}
+
+func TestVerify(t *testing.T) {
+	path := writeRecords(t, 3)
+	lines := readLines(t, path)
+
+	n, err := verifyLines(lines, testKey)
+	if err != nil || n != 3 {
+		t.Fatalf("Verify() = %d, %v, want 3, nil", n, err)
+	}
+}
+
+func TestVerifyEditedLine(t *testing.T) {
+	lines := readLines(t, writeRecords(t, 3))
+	lines[1] = strings.Replace(lines[1], `"status":200`, `"status":403`, 1)
+
+	if _, err := verifyLines(lines, testKey); !errors.Is(err, ErrBrokenChain) || !strings.Contains(err.Error(), "line: 2") {
+		t.Errorf("Verify() error = %v, want broken chain at line 2", err)
+	}
+}
+
+func TestVerifyDeletedLine(t *testing.T) {
+	lines := readLines(t, writeRecords(t, 3))
+
+	// 删除中间的一行
+	middle := []string{lines[0], lines[2]}
+	if _, err := verifyLines(middle, testKey); !errors.Is(err, ErrBrokenChain) || !strings.Contains(err.Error(), "line: 2") {
+		t.Errorf("Verify() error = %v, want broken chain at line 2", err)
+	}
+
+	// 删除第一行
+	if _, err := verifyLines(lines[1:], testKey); !errors.Is(err, ErrBrokenChain) || !strings.Contains(err.Error(), "line: 1") {
+		t.Errorf("Verify() error = %v, want broken chain at line 1", err)
+	}
+}
+
+func TestVerifyRecomputedChain(t *testing.T) {
+	lines := readLines(t, writeRecords(t, 2))
+
+	// 没有密钥时，修改记录后使用 sha256 重新计算无法通过检查
+	i := strings.LastIndex(lines[0], string(macField))
+	content := strings.Replace(lines[0][:i]+"}", `"status":200`, `"status":403`, 1)
+	sum := sha256.Sum256([]byte(content))
+	lines[0] = content[:len(content)-1] + string(macField) + hex.EncodeToString(sum[:]) + `"}`
+
+	if _, err := verifyLines(lines[:1], testKey); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Verify() error = %v, want broken chain", err)
+	}
+}
+
+func TestVerifyWrongKey(t *testing.T) {
+	lines := readLines(t, writeRecords(t, 2))
+
+	if _, err := verifyLines(lines, []byte("another-key")); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("Verify() error = %v, want broken chain", err)
+	}
+
+	if _, err := verifyLines(lines, nil); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("Verify() error = %v, want empty key", err)
+	}
+}
+
+func TestReopen(t *testing.T) {
+	path := writeRecords(t, 2)
+
+	sink, err := NewFileSink(path, testKey)
+	if err != nil {
+		t.Fatalf("NewFileSink() error = %v", err)
+	}
+
+	lines := readLines(t, path)
+	if head := sink.Head(); !strings.HasSuffix(lines[1], `"mac":"`+head+`"}`) {
+		t.Errorf("Head() = %q, want mac of the last line", head)
+	}
+
+	if err := sink.Write(&Record{Principal: "admin", Method: "DELETE", Route: "/user/:id"}); err != nil {
+		t.Fatalf("Write() error = %v", err)
+	}
+	sink.Close()
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+
+	if n, err := Verify(bytes.NewReader(content), testKey); err != nil || n != 3 {
+		t.Errorf("Verify() = %d, %v, want 3, nil", n, err)
+	}
+}
+
+func TestReopenBrokenFile(t *testing.T) {
+	path := writeRecords(t, 2)
+	lines := readLines(t, path)
+	lines[0] = strings.Replace(lines[0], `"admin"`, `"guest"`, 1)
+
+	if err := os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+
+	if _, err := NewFileSink(path, testKey); !errors.Is(err, ErrBrokenChain) {
+		t.Errorf("NewFileSink() error = %v, want broken chain", err)
+	}
+
+	if _, err := NewFileSink(path, nil); !errors.Is(err, ErrEmptyKey) {
+		t.Errorf("NewFileSink() error = %v, want empty key", err)
+	}
+}
@@ -11,6 +11,9 @@ import (
 	zeroapi "github.com/zerogo-hub/zero-api"
 )
 
+// Key 认证通过的账号在 ctx 中的 key
+const Key = "zam_basic_account"
+
 // Config 配置
 type Config struct {
 	users map[string]*user
@@ -55,7 +58,8 @@ func New(config *Config, accounts map[string]string) zeroapi.Handler {
 
 	return func(ctx zeroapi.Context) {
 		h := ctx.Header("Authorization")
-		if c.verify(h) {
+		if account, ok := c.verify(h); ok {
+			ctx.SetValue(Key, account)
 			return
 		}
 
@@ -100,13 +104,13 @@ func (c *Config) init(config *Config, accounts map[string]string) {
 	}
 }
 
-func (c *Config) verify(header string) bool {
+func (c *Config) verify(header string) (string, bool) {
 	if header == "" || len(header) < c.basiclen+1 {
-		return false
+		return "", false
 	}
 
 	if header[:c.basiclen] != c.Basic {
-		return false
+		return "", false
 	}
 
 	if c.users != nil {
@@ -117,14 +121,14 @@ func (c *Config) verify(header string) bool {
 				// 尚未登录过
 				user.logged = true
 				user.expire = time.Now().Add(c.Expires)
-				return true
+				return user.account, true
 			} else if time.Now().After(user.expire) {
 				// 登录超时
 				delete(c.users, header)
-				return false
+				return "", false
 			}
 
-			return true
+			return user.account, true
 		}
 
 		if c.Check != nil {
@@ -134,16 +138,16 @@ func (c *Config) verify(header string) bool {
 				b := string(a)
 				for i := 0; i < len(b); i++ {
 					if b[i] == ':' {
-						return c.Check(b[:i], b[i+1:])
+						return b[:i], c.Check(b[:i], b[i+1:])
 					}
 				}
 			}
 		}
 
-		return false
+		return "", false
 	}
 
-	return false
+	return "", false
 }
 
 func (c *Config) failed(ctx zeroapi.Context) {
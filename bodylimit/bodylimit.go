@@ -5,6 +5,9 @@ import (
 	"net/http"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 限制请求体大小
@@ -25,18 +28,18 @@ func New(method string, limit int64) zeroapi.Handler {
 		if l == -1 || (l == 0 && ctx.Request().Body != nil) {
 			ctx.SetHTTPCode(http.StatusBadRequest)
 			if _, err := ctx.Message(http.StatusBadRequest, "bad request"); err != nil {
-				ctx.App().Logger().Errorf("set message failed, err: %s", err.Error())
+				ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 			}
-			ctx.App().Logger().Warnf("bad request, ctx.Request().ContentLength: %d, method: %s, ip: %s", l, method, ctx.IP())
+			ctx.App().Logger().Warnf("bad request, ctx.Request().ContentLength: %d, method: %s, ip: %s, request_id: %s", l, method, realip.IP(ctx), requestid.Get(ctx))
 			ctx.Stopped()
 			return
 		}
 		if l > limit {
 			ctx.SetHTTPCode(http.StatusRequestEntityTooLarge)
 			if _, err := ctx.Message(http.StatusRequestEntityTooLarge, "request entity too large"); err != nil {
-				ctx.App().Logger().Errorf("set message failed, err: %s", err.Error())
+				ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 			}
-			ctx.App().Logger().Warnf("request entity too large, limit: %d, ctx.Request().ContentLength: %d, ip: %s", limit, l, ctx.IP())
+			ctx.App().Logger().Warnf("request entity too large, limit: %d, ctx.Request().ContentLength: %d, ip: %s, request_id: %s", limit, l, realip.IP(ctx), requestid.Get(ctx))
 			ctx.Stopped()
 			return
 		}
@@ -0,0 +1,159 @@
+package concurrency
+
+import (
+	"container/list"
+	"math"
+	"sync"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// bucket 一个 key 的并发状态
+type bucket struct {
+	// refs 正在使用该 bucket 的请求个数，由 Limiter.lock 保护
+	refs int
+
+	// key、idle 与 lastUsed 由 Limiter.lock 保护
+	// idle 没有请求使用时在 Limiter.idle 中的位置，正在使用时为 nil
+	key      string
+	idle     *list.Element
+	lastUsed time.Time
+
+	lock     sync.Mutex
+	limit    float64
+	inflight int
+	// waiters 排队的请求，先进先出
+	waiters *list.List
+
+	// minRTT ModeGradient 中观察到的最小耗时
+	minRTT time.Duration
+	// samples ModeGradient 中的采样次数，定期重置 minRTT 以适应变化
+	samples int
+}
+
+// gradientResetSamples 每隔多少次采样重置最小耗时
+const gradientResetSamples = 1000
+
+func newBucket(key string, limit float64) *bucket {
+	return &bucket{
+		key:     key,
+		limit:   limit,
+		waiters: list.New(),
+	}
+}
+
+// acquire 获取一个并发名额，达到上限时最多排队等待 timeout
+func (b *bucket) acquire(ctx zeroapi.Context, timeout time.Duration, maxQueue int) bool {
+	b.lock.Lock()
+	if b.inflight < int(b.limit) {
+		b.inflight++
+		b.lock.Unlock()
+		return true
+	}
+
+	if timeout <= 0 || b.waiters.Len() >= maxQueue {
+		b.lock.Unlock()
+		return false
+	}
+
+	ch := make(chan struct{})
+	el := b.waiters.PushBack(ch)
+	b.lock.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-ch:
+		return true
+	case <-timer.C:
+	case <-ctx.Request().Context().Done():
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	select {
+	case <-ch:
+		// 超时的同时获得了名额
+		return true
+	default:
+		b.waiters.Remove(el)
+		return false
+	}
+}
+
+// release 归还名额，根据耗时调整上限，并唤醒排队的请求
+//
+// dropped 表示请求失败，如返回 5xx
+func (b *bucket) release(c *Config, rtt time.Duration, dropped bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	switch c.Mode {
+	case ModeAIMD:
+		b.aimd(c, rtt, dropped)
+	case ModeGradient:
+		b.gradient(c, rtt, dropped)
+	}
+
+	b.inflight--
+
+	// 名额直接转交给排队的请求
+	for b.inflight < int(b.limit) && b.waiters.Len() > 0 {
+		ch := b.waiters.Remove(b.waiters.Front()).(chan struct{})
+		b.inflight++
+		close(ch)
+	}
+}
+
+func (b *bucket) aimd(c *Config, rtt time.Duration, dropped bool) {
+	if dropped || rtt > c.LatencyThreshold {
+		b.setLimit(c, b.limit*c.Backoff)
+		return
+	}
+
+	// 只在接近上限时增加，避免空闲时上限无限增长
+	if float64(b.inflight)*2 >= b.limit {
+		b.setLimit(c, b.limit+1)
+	}
+}
+
+func (b *bucket) gradient(c *Config, rtt time.Duration, dropped bool) {
+	if rtt <= 0 {
+		return
+	}
+
+	b.samples++
+	if b.minRTT == 0 || rtt < b.minRTT || b.samples >= gradientResetSamples {
+		b.minRTT = rtt
+		b.samples = 0
+	}
+
+	if dropped {
+		b.setLimit(c, b.limit*c.Backoff)
+		return
+	}
+
+	// 耗时越接近最小耗时，gradient 越接近 1
+	gradient := math.Max(0.5, math.Min(1, float64(b.minRTT)/float64(rtt)))
+
+	// 允许少量排队，以便发现更高的上限
+	queue := math.Sqrt(b.limit)
+
+	// 平滑调整
+	newLimit := b.limit*gradient + queue
+	b.setLimit(c, b.limit*0.8+newLimit*0.2)
+}
+
+func (b *bucket) setLimit(c *Config, limit float64) {
+	b.limit = math.Max(float64(c.MinLimit), math.Min(float64(c.MaxLimit), limit))
+}
+
+func (b *bucket) stats() (int, int, int) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return int(b.limit), b.inflight, b.waiters.Len()
+}
@@ -0,0 +1,288 @@
+// Package concurrency 并发控制，限制同时处理的请求个数
+//
+// 与限流不同，限流控制请求的速率，并发控制限制正在处理的请求，避免下游变慢时请求堆积
+package concurrency
+
+import (
+	"container/list"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// Mode 并发上限的调整方式
+type Mode int
+
+const (
+	// ModeFixed 固定上限
+	ModeFixed Mode = iota
+
+	// ModeAIMD 加性增、乘性减
+	// 请求耗时超过 LatencyThreshold 或返回 5xx 时，上限乘以 Backoff，否则在接近上限时加 1
+	ModeAIMD
+
+	// ModeGradient 梯度，类似 TCP Vegas
+	// 根据最小耗时与当前耗时的比值调整上限，耗时变长时减小上限，耗时接近最小耗时时增大上限
+	ModeGradient
+)
+
+// Config 配置
+type Config struct {
+	// Limit 最大并发数，默认 100，自适应模式下为初始值
+	Limit int
+
+	// KeyFunc 为请求生成 key，每一个 key 分别计算并发数，为空时全局计算
+	KeyFunc func(ctx zeroapi.Context) string
+
+	// QueueTimeout 达到上限时排队等待的最长时间，默认 0，不等待，立即拒绝
+	QueueTimeout time.Duration
+
+	// MaxQueue 每一个 key 最多排队的请求个数，默认等于 Limit
+	MaxQueue int
+
+	// MaxKeys 按 key 计算时最多保存的 key 个数，默认 10000
+	// 超出时淘汰最久没有请求的 key，正在处理请求的 key 不会被淘汰
+	MaxKeys int
+
+	// IdleTTL 按 key 计算时，key 超过该时间没有请求时被清理，默认 10 分钟
+	// 自适应模式下，key 在空闲期间保留调整后的上限
+	IdleTTL time.Duration
+
+	// Mode 并发上限的调整方式，默认 ModeFixed
+	Mode Mode
+
+	// MinLimit 自适应模式下的最小上限，默认 1
+	MinLimit int
+
+	// MaxLimit 自适应模式下的最大上限，默认 1000
+	MaxLimit int
+
+	// LatencyThreshold ModeAIMD 中认为请求变慢的耗时，默认 1 秒
+	LatencyThreshold time.Duration
+
+	// Backoff ModeAIMD 中减小上限的比例，默认 0.9
+	Backoff float64
+
+	// RejectCode 拒绝时的 HTTP Code，默认 503
+	RejectCode int
+}
+
+// Stats 并发统计
+type Stats struct {
+	// Limit 当前的并发上限，按 key 计算时为所有 key 的上限之和
+	Limit int `json:"limit"`
+	// Inflight 正在处理的请求个数
+	Inflight int `json:"inflight"`
+	// Waiting 正在排队的请求个数
+	Waiting int `json:"waiting"`
+	// Keys 当前的 key 个数
+	Keys int `json:"keys"`
+	// Rejected 被拒绝的请求个数
+	Rejected uint64 `json:"rejected"`
+}
+
+// Limiter 并发控制
+//
+// 使用 a.Use(l.Handle) 注册为中间件
+type Limiter struct {
+	c *Config
+
+	lock    *sync.Mutex
+	buckets map[string]*bucket
+
+	// idle 没有请求使用的 bucket，头部为最近使用
+	idle *list.List
+
+	rejected atomic.Uint64
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		Limit:            100,
+		MaxKeys:          10000,
+		IdleTTL:          10 * time.Minute,
+		MinLimit:         1,
+		MaxLimit:         1000,
+		LatencyThreshold: time.Second,
+		Backoff:          0.9,
+		RejectCode:       http.StatusServiceUnavailable,
+	}
+}
+
+// New 并发控制
+//
+// config 为 nil 时使用默认设置，全局最多同时处理 100 个请求
+func New(config *Config) *Limiter {
+	c := defaultConfig()
+	if config != nil {
+		if config.Limit > 0 {
+			c.Limit = config.Limit
+		}
+		c.KeyFunc = config.KeyFunc
+		c.QueueTimeout = config.QueueTimeout
+		c.MaxQueue = config.MaxQueue
+		if config.MaxKeys > 0 {
+			c.MaxKeys = config.MaxKeys
+		}
+		if config.IdleTTL > 0 {
+			c.IdleTTL = config.IdleTTL
+		}
+		c.Mode = config.Mode
+		if config.MinLimit > 0 {
+			c.MinLimit = config.MinLimit
+		}
+		if config.MaxLimit > 0 {
+			c.MaxLimit = config.MaxLimit
+		}
+		if config.LatencyThreshold > 0 {
+			c.LatencyThreshold = config.LatencyThreshold
+		}
+		if config.Backoff > 0 && config.Backoff < 1 {
+			c.Backoff = config.Backoff
+		}
+		if config.RejectCode > 0 {
+			c.RejectCode = config.RejectCode
+		}
+	}
+
+	if c.MaxQueue <= 0 {
+		c.MaxQueue = c.Limit
+	}
+
+	return &Limiter{
+		c:       c,
+		lock:    &sync.Mutex{},
+		buckets: make(map[string]*bucket),
+		idle:    list.New(),
+	}
+}
+
+// Handle 中间件
+func (l *Limiter) Handle(ctx zeroapi.Context) {
+	if ctx.Method() == http.MethodOptions {
+		return
+	}
+
+	key := ""
+	if l.c.KeyFunc != nil {
+		key = l.c.KeyFunc(ctx)
+	}
+
+	b := l.bucket(key)
+
+	if !b.acquire(ctx, l.c.QueueTimeout, l.c.MaxQueue) {
+		l.rejected.Add(1)
+		l.done(key, b)
+
+		ctx.Stopped()
+		ctx.SetHTTPCode(l.c.RejectCode)
+		ctx.App().Logger().Errorf("concurrency limit: %s, method: %s, path: %s, ip: %s, request_id: %s", key, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
+		return
+	}
+
+	start := time.Now()
+
+	ctx.AppendEnd(func() error {
+		b.release(l.c, time.Since(start), ctx.HTTPCode() >= http.StatusInternalServerError)
+		l.done(key, b)
+		return nil
+	})
+}
+
+// Stats 获取并发统计
+func (l *Limiter) Stats() Stats {
+	l.lock.Lock()
+	buckets := make([]*bucket, 0, len(l.buckets))
+	for _, b := range l.buckets {
+		buckets = append(buckets, b)
+	}
+	l.lock.Unlock()
+
+	stats := Stats{
+		Keys:     len(buckets),
+		Rejected: l.rejected.Load(),
+	}
+
+	if len(buckets) == 0 {
+		stats.Limit = l.c.Limit
+		return stats
+	}
+
+	for _, b := range buckets {
+		limit, inflight, waiting := b.stats()
+		stats.Limit += limit
+		stats.Inflight += inflight
+		stats.Waiting += waiting
+	}
+
+	return stats
+}
+
+// bucket 获取 key 对应的 bucket，并增加引用
+func (l *Limiter) bucket(key string) *bucket {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b, ok := l.buckets[key]
+	if ok {
+		if b.idle != nil {
+			l.idle.Remove(b.idle)
+			b.idle = nil
+		}
+	} else {
+		l.expire(time.Now())
+
+		// 超出 MaxKeys 时淘汰最久没有请求的 bucket
+		if len(l.buckets) >= l.c.MaxKeys {
+			if oldest := l.idle.Back(); oldest != nil {
+				l.remove(oldest)
+			}
+		}
+
+		b = newBucket(key, float64(l.c.Limit))
+		l.buckets[key] = b
+	}
+	b.refs++
+
+	return b
+}
+
+// done 减少引用，按 key 计算时，没有请求使用的 bucket 保留在 idle 中，保留自适应调整后的上限
+func (l *Limiter) done(key string, b *bucket) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	b.refs--
+	if b.refs == 0 && key != "" {
+		b.lastUsed = time.Now()
+		b.idle = l.idle.PushFront(b)
+	}
+}
+
+// expire 清理超过 IdleTTL 没有请求的 bucket，调用时需持有 lock
+func (l *Limiter) expire(now time.Time) {
+	deadline := now.Add(-l.c.IdleTTL)
+
+	// 从尾部(最久没有请求)开始清理
+	for el := l.idle.Back(); el != nil; {
+		if el.Value.(*bucket).lastUsed.After(deadline) {
+			break
+		}
+		prev := el.Prev()
+		l.remove(el)
+		el = prev
+	}
+}
+
+// remove 删除空闲的 bucket，调用时需持有 lock
+func (l *Limiter) remove(el *list.Element) {
+	b := l.idle.Remove(el).(*bucket)
+	b.idle = nil
+	delete(l.buckets, b.key)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamconcurrency "github.com/zerogo-hub/zero-api-middleware/concurrency"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	time.Sleep(time.Second)
+
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", pid, pid)
+}
+
+func main() {
+	a := app.New()
+
+	// 最多同时处理 2 个请求，其余请求最多排队 3 秒
+	l := zamconcurrency.New(&zamconcurrency.Config{
+		Limit:        2,
+		QueueTimeout: 3 * time.Second,
+	})
+	a.Use(l.Handle)
+
+	// 自适应模式，根据请求耗时自动调整上限
+	// l := zamconcurrency.New(&zamconcurrency.Config{
+	// 	Limit:    20,
+	// 	Mode:     zamconcurrency.ModeGradient,
+	// 	MaxLimit: 200,
+	// })
+
+	a.Get("/", helloworldHandle)
+
+	a.Get("/stats", func(ctx zeroapi.Context) {
+		stats := l.Stats()
+		ctx.Textf("limit: %d, inflight: %d, waiting: %d", stats.Limit, stats.Inflight, stats.Waiting)
+	})
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
+
+// 同时发送多个请求，超出并发上限且排队超时的请求返回 503
+// 命令: for i in $(seq 1 10); do curl -s -o /dev/null -w "%{http_code}\n" http://127.0.0.1:8877 & done
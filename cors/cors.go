@@ -5,8 +5,12 @@ import (
 	"net/http"
 	"regexp"
 	"strings"
+	"sync"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // 简单请求，浏览器直接发出 CORS 请求
@@ -16,6 +20,7 @@ import (
 // Origin: 表示请求来自哪个源
 // Access-Control-Request-Method: 该字段必须
 // Access-Control-Request-Headers: 使用逗号分隔，指定浏览器发出 CORS 请求会额外发送的头信息字段
+// Access-Control-Request-Private-Network: 公网页面访问私有网络(如内网、localhost)时，浏览器会携带该字段，值为 true
 //
 // 简单请求，需要同时满足以下两个条件
 // 1 请求必须是以下三种方法之一: HEAD, GET, POST
@@ -43,6 +48,7 @@ type Config struct {
 	AccessControlExposeHeaders []string
 
 	// AccessControlAllowMethods (非简单请求的响应)所有允许请求跨域的方法，默认为 ["HEAD", "GET", "POST", "PUT", "PATCH", "DELETE"]
+	// 与浏览器相同，只有 DELETE, GET, HEAD, OPTIONS, POST, PUT 不区分大小写，其它方法如 PATCH 需要与请求中的大小写一致
 	AccessControlAllowMethods []string
 
 	// AccessControlAllowHeaders （非简单请求的响应）允许出默认之外，允许发送到服务端的 HEADER
@@ -53,6 +59,69 @@ type Config struct {
 	// 单位：秒，不同的浏览器有上限
 	AccessControlMaxAge string
 
+	// AccessControlAllowPrivateNetwork (非简单请求的响应)是否允许公网页面访问私有网络，默认 false
+	// 预检请求携带 Access-Control-Request-Private-Network: true 时，返回 Access-Control-Allow-Private-Network: true
+	// https://wicg.github.io/private-network-access/
+	AccessControlAllowPrivateNetwork bool
+
+	// NonBlocking 非阻塞模式，默认 false
+	// 开启后，来源等检查未通过时不返回 403，只是不设置跨域响应头，由浏览器拦截
+	NonBlocking bool
+
+	// OptionsPassthrough 预检请求设置跨域响应头后，是否继续执行后续的 OPTIONS 处理函数，默认 false
+	OptionsPassthrough bool
+
+	// OnDecision 每次检查后调用，可用于记录检查结果，定位浏览器报告的跨域错误
+	OnDecision func(ctx zeroapi.Context, decision *Decision)
+
+	// Debug 调试模式，开启后在响应中添加 X-Cors-Debug，说明检查结果及未通过的规则，仅用于开发环境
+	Debug bool
+
+	// AllowOriginFunc 动态检查来源，在 AccessControlAllowOrigin 均不匹配时调用，返回 true 表示允许
+	// 可用于从数据库等位置加载的租户白名单
+	AllowOriginFunc func(ctx zeroapi.Context, origin string) bool
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		AccessControlAllowOrigin:      []string{"*"},
+		AccessControlAllowCredentials: false,
+		AccessControlExposeHeaders:    []string{},
+		AccessControlAllowMethods: []string{
+			http.MethodHead,
+			http.MethodGet,
+			http.MethodPost,
+			http.MethodPut,
+			http.MethodPatch,
+			http.MethodDelete,
+		},
+		AccessControlAllowHeaders: []string{
+			"Origin",
+			"Accept",
+			"Accept-Language",
+			"Content-Language",
+			"Content-Type",
+		},
+		AccessControlMaxAge: "600",
+	}
+}
+
+// Policy 跨域策略，只能通过 NewPolicy 创建
+//
+// 创建后配置不可修改，只能通过 SetAllowOrigin 更新允许的来源
+type Policy struct {
+	// c 创建时拷贝的配置
+	c *Config
+
+	// stats 检查结果的计数
+	stats *stats
+
+	// lock 保护 accessControlAllowOrigins、accessControlAllOrigin 与 accessControlAllowOrigin，允许在运行时更新来源
+	lock *sync.RWMutex
+
+	// accessControlAllowOrigins 当前允许跨域的来源
+	accessControlAllowOrigins []string
+
 	// accessControlAllOrigin 是否不限制任何来源，当 AccessControlAllowOrigin 含有 "*" 时为 true
 	accessControlAllOrigin bool
 
@@ -65,172 +134,292 @@ type Config struct {
 	// accessControlAllowMethods 将 AccessControlAllowMethods 转为字符串形式存储，使用 "," 做为分隔符
 	accessControlAllowMethods string
 
-	// accessControlAllowHeaders 将 AccessControlAllowHeaders 转为字符串形式存储，使用 "," 做为分隔符
-	accessControlAllowHeaders string
-}
-
-var defaultConfig = &Config{
-	AccessControlAllowOrigin:      []string{"*"},
-	AccessControlAllowCredentials: false,
-	AccessControlExposeHeaders:    []string{},
-	AccessControlAllowMethods: []string{
-		http.MethodHead,
-		http.MethodGet,
-		http.MethodPost,
-		http.MethodPut,
-		http.MethodPatch,
-		http.MethodDelete,
-	},
-	AccessControlAllowHeaders: []string{
-		"Origin",
-		"Accept",
-		"Accept-Language",
-		"Content-Language",
-		"Content-Type",
-	},
-	AccessControlMaxAge: "600",
+	// accessControlAllowHeaders 将 AccessControlAllowHeaders 规范化后存储于此，用于快速查找
+	accessControlAllowHeaders map[string]struct{}
 }
 
 // New 跨域控制
 //
-// config 为 nil 时使用默认设置
+// config 为 nil 时使用默认设置，配置无效时 panic，见 Config.Validate
 func New(config *Config) zeroapi.Handler {
-	c := defaultConfig
+	p, err := NewPolicy(config)
+	if err != nil {
+		panic(err)
+	}
+
+	return p.Handler()
+}
+
+// NewPolicy 创建一个跨域策略，可以注册到 Registry 中，或者在运行时通过 SetAllowOrigin 更新允许的来源
+//
+// config 为 nil 时使用默认设置，配置无效时返回错误，见 Config.Validate
+//
+// 每次调用都会创建独立的策略，不会影响其它策略，也不受之后修改 config 的影响
+func NewPolicy(config *Config) (*Policy, error) {
+	c := defaultConfig()
 	c.init(config)
 
-	return func(ctx zeroapi.Context) {
-		// 预检请求
-		if ctx.Method() == zeroapi.MethodOptions && len(ctx.Header("Access-Control-Request-Method")) != 0 {
-			if c.checkPreflight(ctx) {
-				// 预检通过
-				c.checkPreflightSuccess(ctx)
-			} else {
-				// 预检未通过
-				c.checkPreflightFailed(ctx)
-			}
-
-			return
-		}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	return c.compile(), nil
+}
+
+// Handler 返回使用该策略的中间件
+func (p *Policy) Handler() zeroapi.Handler {
+	return p.handle
+}
+
+// AllowOrigin 当前允许跨域的来源
+func (p *Policy) AllowOrigin() []string {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
 
-		// 浏览器的正常请求
-		if c.checkRequest(ctx) {
-			// 检查通过
-			c.checkRequestSuccess(ctx)
+	return copyStrings(p.accessControlAllowOrigins)
+}
+
+func (p *Policy) handle(ctx zeroapi.Context) {
+	p.setVary(ctx)
+
+	// 没有 Origin 的请求不是跨域请求，如同源请求、curl、服务间调用，直接放行
+	if len(ctx.Header("Origin")) == 0 {
+		return
+	}
+
+	// 预检请求
+	if ctx.Method() == zeroapi.MethodOptions && len(ctx.Header("Access-Control-Request-Method")) != 0 {
+		reason := p.checkPreflight(ctx)
+		p.report(ctx, true, reason)
+
+		if reason == ReasonNone {
+			// 预检通过
+			p.checkPreflightSuccess(ctx)
 		} else {
-			// 检查未通过
-			c.checkRequestFailed(ctx)
+			// 预检未通过
+			p.checkPreflightFailed(ctx, reason)
 		}
+
+		return
+	}
+
+	// 浏览器的正常请求
+	reason := p.checkRequest(ctx)
+	p.report(ctx, false, reason)
+
+	if reason == ReasonNone {
+		// 检查通过
+		p.checkRequestSuccess(ctx)
+	} else {
+		// 检查未通过
+		p.checkRequestFailed(ctx, reason)
 	}
 }
 
-// checkPreflight 预检检查
-func (c *Config) checkPreflight(ctx zeroapi.Context) bool {
+// SetAllowOrigin 在运行时更新允许跨域的来源，无需重启服务
+//
+// 来源无效时返回错误，并保留原有的来源
+func (p *Policy) SetAllowOrigin(origins []string) error {
+	if err := validateOrigin(origins, p.c.AccessControlAllowCredentials); err != nil {
+		return err
+	}
 
-	if !c.checkOrigin(ctx) {
-		return false
+	origins = copyStrings(origins)
+	all, patterns := compileOrigin(origins)
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	p.accessControlAllowOrigins = origins
+	p.accessControlAllOrigin = all
+	p.accessControlAllowOrigin = patterns
+
+	return nil
+}
+
+// checkPreflight 预检检查，返回未通过的规则
+func (p *Policy) checkPreflight(ctx zeroapi.Context) Reason {
+
+	if !p.checkOrigin(ctx) {
+		return ReasonOrigin
 	}
 
 	method := ctx.Header("Access-Control-Request-Method")
-	if !c.checkMethod(method) {
-		return false
+	if !p.checkMethod(method) {
+		return ReasonMethod
 	}
 
-	if !c.checkHeader(ctx) {
-		return false
+	if !p.checkHeader(ctx) {
+		return ReasonHeader
 	}
 
-	return true
+	return ReasonNone
 }
 
-func (c *Config) checkPreflightSuccess(ctx zeroapi.Context) {
-	if c.accessControlAllOrigin && !c.AccessControlAllowCredentials {
+func (p *Policy) checkPreflightSuccess(ctx zeroapi.Context) {
+	if p.allOrigin() && !p.c.AccessControlAllowCredentials {
 		// Cookie 遵循同源政策，如果允许携带 Cooke，则不允许设置 Origin 为 *
 		ctx.AddHeader("Access-Control-Allow-Origin", "*")
 	} else {
 		ctx.AddHeader("Access-Control-Allow-Origin", ctx.Header("Origin"))
 	}
 
-	ctx.AddHeader("Access-Control-Allow-Methods", c.accessControlAllowMethods)
+	ctx.AddHeader("Access-Control-Allow-Methods", p.accessControlAllowMethods)
 
-	headers := ctx.Header("Access-Control-Request-Headers")
+	// 只返回已通过检查的请求头，而非全部允许的请求头
+	headers := requestHeaders(ctx)
 	if len(headers) > 0 {
-		ctx.AddHeader("Access-Control-Allow-Headers", c.accessControlAllowHeaders)
+		ctx.AddHeader("Access-Control-Allow-Headers", strings.Join(headers, ","))
 	}
 
-	if c.AccessControlAllowCredentials {
+	if p.c.AccessControlAllowPrivateNetwork && ctx.Header("Access-Control-Request-Private-Network") == "true" {
+		ctx.AddHeader("Access-Control-Allow-Private-Network", "true")
+	}
+
+	if p.c.AccessControlAllowCredentials {
 		ctx.AddHeader("Access-Control-Allow-Credentials", "true")
 	}
 
-	if c.AccessControlMaxAge != "" {
-		ctx.AddHeader("Access-Control-Max-Age", c.AccessControlMaxAge)
+	if p.c.AccessControlMaxAge != "" {
+		ctx.AddHeader("Access-Control-Max-Age", p.c.AccessControlMaxAge)
 	}
 
-	ctx.Stopped()
-	ctx.SetHTTPCode(http.StatusNoContent)
+	p.endPreflight(ctx)
 }
 
-func (c *Config) checkPreflightFailed(ctx zeroapi.Context) {
+func (p *Policy) checkPreflightFailed(ctx zeroapi.Context, reason Reason) {
+	if p.c.NonBlocking {
+		// 不设置跨域响应头，由浏览器拦截
+		ctx.App().Logger().Warnf("preflight failed, origin: %s, method: %s, reason: %s, ip: %s, request_id: %s", ctx.Header("Origin"), ctx.Header("Access-Control-Request-Method"), reason, realip.IP(ctx), requestid.Get(ctx))
+		p.endPreflight(ctx)
+		return
+	}
+
 	ctx.Stopped()
 	ctx.SetHTTPCode(http.StatusForbidden)
-	ctx.App().Logger().Errorf("preflight failed, origin: %s, method: %s, ip: %s", ctx.Header("Origin"), ctx.Method(), ctx.IP())
+	ctx.App().Logger().Errorf("preflight failed, origin: %s, method: %s, reason: %s, ip: %s, request_id: %s", ctx.Header("Origin"), ctx.Header("Access-Control-Request-Method"), reason, realip.IP(ctx), requestid.Get(ctx))
+}
+
+// endPreflight 结束预检请求，开启 OptionsPassthrough 时交由后续的处理函数
+func (p *Policy) endPreflight(ctx zeroapi.Context) {
+	if p.c.OptionsPassthrough {
+		return
+	}
+
+	ctx.Stopped()
+	ctx.SetHTTPCode(http.StatusNoContent)
 }
 
-func (c *Config) checkRequest(ctx zeroapi.Context) bool {
+// checkRequest 正常请求检查，返回未通过的规则
+func (p *Policy) checkRequest(ctx zeroapi.Context) Reason {
 
-	if !c.checkOrigin(ctx) {
-		return false
+	if !p.checkOrigin(ctx) {
+		return ReasonOrigin
 	}
 
 	method := ctx.Method()
-	return c.checkMethod(method)
+	if !p.checkMethod(method) {
+		return ReasonMethod
+	}
+
+	return ReasonNone
 }
 
-func (c *Config) checkRequestSuccess(ctx zeroapi.Context) {
-	if c.accessControlAllOrigin && !c.AccessControlAllowCredentials {
+func (p *Policy) checkRequestSuccess(ctx zeroapi.Context) {
+	if p.allOrigin() && !p.c.AccessControlAllowCredentials {
 		// Cookie 遵循同源政策，如果允许携带 Cooke，则不允许设置 Origin 为 *
 		ctx.AddHeader("Access-Control-Allow-Origin", "*")
 	} else {
 		ctx.AddHeader("Access-Control-Allow-Origin", ctx.Header("Origin"))
 	}
 
-	if c.AccessControlAllowCredentials {
+	if p.c.AccessControlAllowCredentials {
 		ctx.AddHeader("Access-Control-Allow-Credentials", "true")
 	}
 
-	if len(c.accessControlExposeHeaders) > 0 {
-		ctx.AddHeader("Access-Control-Expose-Headers", c.accessControlExposeHeaders)
+	if len(p.accessControlExposeHeaders) > 0 {
+		ctx.AddHeader("Access-Control-Expose-Headers", p.accessControlExposeHeaders)
 	}
 }
 
-func (c *Config) checkRequestFailed(ctx zeroapi.Context) {
+func (p *Policy) checkRequestFailed(ctx zeroapi.Context, reason Reason) {
+	if p.c.NonBlocking {
+		// 不设置跨域响应头，由浏览器拦截
+		ctx.App().Logger().Warnf("request failed, origin: %s, method: %s, reason: %s, ip: %s, request_id: %s", ctx.Header("Origin"), ctx.Method(), reason, realip.IP(ctx), requestid.Get(ctx))
+		return
+	}
+
 	ctx.SetHTTPCode(http.StatusForbidden)
-	ctx.App().Logger().Errorf("request failed, origin: %s, method: %s, ip: %s", ctx.Header("Origin"), ctx.Method(), ctx.IP())
+	ctx.App().Logger().Errorf("request failed, origin: %s, method: %s, reason: %s, ip: %s, request_id: %s", ctx.Header("Origin"), ctx.Method(), reason, realip.IP(ctx), requestid.Get(ctx))
 	ctx.Stopped()
 }
 
 func (c *Config) init(config *Config) {
 	if config != nil {
+		// 拷贝一份，避免之后修改 config 影响到本策略
 		if len(config.AccessControlAllowOrigin) != 0 {
-			c.AccessControlAllowOrigin = config.AccessControlAllowOrigin
+			c.AccessControlAllowOrigin = copyStrings(config.AccessControlAllowOrigin)
 		}
 		c.AccessControlAllowCredentials = config.AccessControlAllowCredentials
 		if len(config.AccessControlExposeHeaders) != 0 {
-			c.AccessControlExposeHeaders = config.AccessControlExposeHeaders
+			c.AccessControlExposeHeaders = copyStrings(config.AccessControlExposeHeaders)
 		}
 		if len(config.AccessControlAllowMethods) != 0 {
-			c.AccessControlAllowMethods = config.AccessControlAllowMethods
+			c.AccessControlAllowMethods = copyStrings(config.AccessControlAllowMethods)
 		}
 		if len(config.AccessControlAllowHeaders) != 0 {
-			c.AccessControlAllowHeaders = config.AccessControlAllowHeaders
+			c.AccessControlAllowHeaders = copyStrings(config.AccessControlAllowHeaders)
 		}
 		if config.AccessControlMaxAge != "" {
 			c.AccessControlMaxAge = config.AccessControlMaxAge
 		}
+		c.AccessControlAllowPrivateNetwork = config.AccessControlAllowPrivateNetwork
+		c.NonBlocking = config.NonBlocking
+		c.OptionsPassthrough = config.OptionsPassthrough
+		c.OnDecision = config.OnDecision
+		c.Debug = config.Debug
+		c.AllowOriginFunc = config.AllowOriginFunc
+	}
+}
+
+// compile 将配置解析为便于检查的形式
+func (c *Config) compile() *Policy {
+	// 与 checkMethod 一致，避免配置的 "get" 永远不匹配
+	for i, method := range c.AccessControlAllowMethods {
+		c.AccessControlAllowMethods[i] = normalizeMethod(method)
+	}
+
+	p := &Policy{
+		c:                          c,
+		stats:                      &stats{},
+		lock:                       &sync.RWMutex{},
+		accessControlAllowOrigins:  c.AccessControlAllowOrigin,
+		accessControlExposeHeaders: strings.Join(c.AccessControlExposeHeaders, ","),
+		accessControlAllowMethods:  strings.Join(c.AccessControlAllowMethods, ","),
+		accessControlAllowHeaders:  make(map[string]struct{}, len(c.AccessControlAllowHeaders)),
 	}
 
-	for _, origin := range c.AccessControlAllowOrigin {
+	p.accessControlAllOrigin, p.accessControlAllowOrigin = compileOrigin(c.AccessControlAllowOrigin)
+
+	for _, header := range c.AccessControlAllowHeaders {
+		p.accessControlAllowHeaders[http.CanonicalHeaderKey(strings.TrimSpace(header))] = struct{}{}
+	}
+
+	return p
+}
+
+func copyStrings(l []string) []string {
+	return append(make([]string, 0, len(l)), l...)
+}
+
+// compileOrigin 将来源转为正则表达式，支持通配符 "*" 与 "?"
+func compileOrigin(origins []string) (bool, []*regexp.Regexp) {
+	all := false
+	patterns := make([]*regexp.Regexp, 0, len(origins))
+
+	for _, origin := range origins {
 		if origin == "*" {
-			c.accessControlAllOrigin = true
+			all = true
 			continue
 		}
 
@@ -238,17 +427,23 @@ func (c *Config) init(config *Config) {
 		pattern = strings.Replace(pattern, "\\*", ".*", -1)
 		pattern = strings.Replace(pattern, "\\?", ".", -1)
 		p := "^" + pattern + "$"
-		c.accessControlAllowOrigin = append(c.accessControlAllowOrigin, regexp.MustCompile(p))
+		patterns = append(patterns, regexp.MustCompile(p))
 	}
 
-	c.accessControlExposeHeaders = strings.Join(c.AccessControlExposeHeaders, ",")
-	c.accessControlAllowMethods = strings.Join(c.AccessControlAllowMethods, ",")
-	c.accessControlAllowHeaders = strings.Join(c.AccessControlAllowHeaders, ",")
+	return all, patterns
+}
+
+// allOrigin 是否不限制任何来源
+func (p *Policy) allOrigin() bool {
+	p.lock.RLock()
+	defer p.lock.RUnlock()
+
+	return p.accessControlAllOrigin
 }
 
 // checkOrigin 检查 ORIGIN
-func (c *Config) checkOrigin(ctx zeroapi.Context) bool {
-	if c.accessControlAllOrigin {
+func (p *Policy) checkOrigin(ctx zeroapi.Context) bool {
+	if p.allOrigin() {
 		return true
 	}
 
@@ -257,24 +452,32 @@ func (c *Config) checkOrigin(ctx zeroapi.Context) bool {
 		return false
 	}
 
-	for _, r := range c.accessControlAllowOrigin {
+	p.lock.RLock()
+	patterns := p.accessControlAllowOrigin
+	p.lock.RUnlock()
+
+	for _, r := range patterns {
 		if r.MatchString(origin) {
 			return true
 		}
 	}
 
+	if p.c.AllowOriginFunc != nil {
+		return p.c.AllowOriginFunc(ctx, origin)
+	}
+
 	return false
 }
 
-// checkMethod 检查 Method
-func (c *Config) checkMethod(method string) bool {
+// checkMethod 检查 Method，与浏览器相同，只有 normalizeMethod 中的方法不区分大小写
+func (p *Policy) checkMethod(method string) bool {
 	if len(method) == 0 {
 		return false
 	}
 
-	m := strings.ToUpper(method)
+	m := normalizeMethod(method)
 
-	for _, v := range c.AccessControlAllowMethods {
+	for _, v := range p.c.AccessControlAllowMethods {
 		if m == v {
 			return true
 		}
@@ -283,43 +486,71 @@ func (c *Config) checkMethod(method string) bool {
 	return false
 }
 
+// normalizeMethod 与 Fetch 标准相同，只将 DELETE, GET, HEAD, OPTIONS, POST, PUT 转为大写，其它方法保持原样
+//
+// 浏览器发送的 "patch" 不会转为 "PATCH"，与 Access-Control-Allow-Methods 比较时区分大小写
+func normalizeMethod(method string) string {
+	m := strings.ToUpper(method)
+	switch m {
+	case http.MethodDelete, http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPost, http.MethodPut:
+		return m
+	}
+
+	return method
+}
+
 // checkHeader 检查 HEADER
-func (c *Config) checkHeader(ctx zeroapi.Context) bool {
+func (p *Policy) checkHeader(ctx zeroapi.Context) bool {
+	for _, key := range requestHeaders(ctx) {
+		// 有一个不允许
+		if _, ok := p.accessControlAllowHeaders[key]; !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// requestHeaders 解析 Access-Control-Request-Headers，去除空白与空项，并转为规范化形式
+func requestHeaders(ctx zeroapi.Context) []string {
 	s := ctx.Header("Access-Control-Request-Headers")
 	if len(s) == 0 {
-		return true
-	}
-	headers := strings.Split(s, ",")
-	if len(headers) == 0 {
-		return true
+		return nil
 	}
 
+	headers := strings.Split(s, ",")
 	keys := make([]string, 0, len(headers))
 	for _, header := range headers {
+		header = strings.TrimSpace(header)
+		if len(header) == 0 {
+			continue
+		}
+
 		// http.CanonicalHeaderKey: 返回header key的规范话形式
 		// 规范化形式是以"-"为分隔符，每一部分都是首字母大写，其他字母小写
 		// 例如"accept-encoding" 的标准化形式是 "Accept-Encoding"
-
-		key := http.CanonicalHeaderKey(header)
-		keys = append(keys, key)
+		keys = append(keys, http.CanonicalHeaderKey(header))
 	}
 
-	for _, key := range keys {
-		found := false
-
-		for _, v := range c.AccessControlAllowHeaders {
+	return keys
+}
 
-			if key == v {
-				found = true
-				break
-			}
+// setVary 当响应内容与请求的 Origin 有关时，设置 Vary，避免 CDN 等缓存了错误的 Access-Control-Allow-Origin
+func (p *Policy) setVary(ctx zeroapi.Context) {
+	if ctx.Method() == zeroapi.MethodOptions && len(ctx.Header("Access-Control-Request-Method")) != 0 {
+		// 预检响应中的 Access-Control-Allow-Headers 与 Access-Control-Allow-Private-Network 与请求有关
+		if p.c.AccessControlAllowPrivateNetwork {
+			ctx.AddHeader("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers, Access-Control-Request-Private-Network")
+		} else {
+			ctx.AddHeader("Vary", "Origin, Access-Control-Request-Method, Access-Control-Request-Headers")
 		}
+		return
+	}
 
-		// 有一个不允许
-		if !found {
-			return false
-		}
+	if p.allOrigin() && !p.c.AccessControlAllowCredentials {
+		// 所有来源均返回 "*"，与 Origin 无关
+		return
 	}
 
-	return true
+	ctx.AddHeader("Vary", "Origin")
 }
@@ -0,0 +1,440 @@
+package cors
+
+import (
+	"net/http"
+	"testing"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zerologger "github.com/zerogo-hub/zero-helper/logger"
+)
+
+// testContext 只实现跨域检查使用的方法
+type testContext struct {
+	zeroapi.Context
+
+	method   string
+	request  http.Header
+	response http.Header
+	code     int
+	stopped  bool
+}
+
+func newTestContext(method string, headers map[string]string) *testContext {
+	ctx := &testContext{
+		method:   method,
+		request:  http.Header{},
+		response: http.Header{},
+	}
+
+	for k, v := range headers {
+		ctx.request.Set(k, v)
+	}
+
+	return ctx
+}
+
+func (ctx *testContext) App() zeroapi.App             { return testApp{} }
+func (ctx *testContext) Method() string               { return ctx.method }
+func (ctx *testContext) IP() string                   { return "127.0.0.1" }
+func (ctx *testContext) Header(key string) string     { return ctx.request.Get(key) }
+func (ctx *testContext) AddHeader(key, value string)  { ctx.response.Add(key, value) }
+func (ctx *testContext) SetHeader(key, value string)  { ctx.response.Set(key, value) }
+func (ctx *testContext) Stopped()                     { ctx.stopped = true }
+func (ctx *testContext) SetHTTPCode(code int)         { ctx.code = code }
+func (ctx *testContext) Value(key string) interface{} { return nil }
+
+type testApp struct {
+	zeroapi.App
+}
+
+func (testApp) Logger() zerologger.Logger { return testLogger{} }
+
+type testLogger struct {
+	zerologger.Logger
+}
+
+func (testLogger) Warnf(format string, v ...interface{})  {}
+func (testLogger) Errorf(format string, v ...interface{}) {}
+
+const preflightVary = "Origin, Access-Control-Request-Method, Access-Control-Request-Headers"
+
+func TestPreflight(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		headers map[string]string
+
+		wantReason  Reason
+		wantCode    int
+		wantStopped bool
+		// wantHeaders 响应头，值为空字符串时要求不存在该响应头
+		wantHeaders map[string]string
+	}{
+		{
+			name:   "default policy allows any origin",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "PUT",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "*",
+				"Access-Control-Allow-Methods":     "HEAD,GET,POST,PUT,PATCH,DELETE",
+				"Access-Control-Allow-Headers":     "",
+				"Access-Control-Allow-Credentials": "",
+				"Access-Control-Max-Age":           "600",
+				"Vary":                             preflightVary,
+			},
+		},
+		{
+			name: "credentials echo the origin",
+			config: &Config{
+				AccessControlAllowOrigin:      []string{"https://api.abc.com"},
+				AccessControlAllowCredentials: true,
+			},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "POST",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":      "https://api.abc.com",
+				"Access-Control-Allow-Credentials": "true",
+			},
+		},
+		{
+			name:   "wildcard origin matches subdomain",
+			config: &Config{AccessControlAllowOrigin: []string{"https://*.abc.com"}},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://api.abc.com",
+			},
+		},
+		{
+			name:   "wildcard origin does not match suffix of another host",
+			config: &Config{AccessControlAllowOrigin: []string{"https://*.abc.com"}},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com.evil.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantReason:  ReasonOrigin,
+			wantCode:    http.StatusForbidden,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin":  "",
+				"Access-Control-Allow-Methods": "",
+			},
+		},
+		{
+			name:   "origin scheme must match",
+			config: &Config{AccessControlAllowOrigin: []string{"https://api.abc.com"}},
+			headers: map[string]string{
+				"Origin":                        "http://api.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantReason:  ReasonOrigin,
+			wantCode:    http.StatusForbidden,
+			wantStopped: true,
+		},
+		{
+			name: "allow origin func",
+			config: &Config{
+				AccessControlAllowOrigin: []string{"https://api.abc.com"},
+				AllowOriginFunc: func(ctx zeroapi.Context, origin string) bool {
+					return origin == "https://tenant.abc.com"
+				},
+			},
+			headers: map[string]string{
+				"Origin":                        "https://tenant.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "https://tenant.abc.com",
+			},
+		},
+		{
+			name:   "method not allowed",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "TRACE",
+			},
+			wantReason:  ReasonMethod,
+			wantCode:    http.StatusForbidden,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:   "normalized method is case insensitive",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "delete",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+		},
+		{
+			name:   "other methods are case sensitive",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "patch",
+			},
+			wantReason:  ReasonMethod,
+			wantCode:    http.StatusForbidden,
+			wantStopped: true,
+		},
+		{
+			name:   "request headers are trimmed and canonicalized",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                         "https://api.abc.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": " content-type, ,ACCEPT ",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Headers": "Content-Type,Accept",
+			},
+		},
+		{
+			name:   "header not allowed",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                         "https://api.abc.com",
+				"Access-Control-Request-Method":  "POST",
+				"Access-Control-Request-Headers": "Content-Type, X-Custom",
+			},
+			wantReason:  ReasonHeader,
+			wantCode:    http.StatusForbidden,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Headers": "",
+			},
+		},
+		{
+			name:   "private network allowed",
+			config: &Config{AccessControlAllowPrivateNetwork: true},
+			headers: map[string]string{
+				"Origin":                                 "https://api.abc.com",
+				"Access-Control-Request-Method":          "GET",
+				"Access-Control-Request-Private-Network": "true",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Private-Network": "true",
+				"Vary":                                 preflightVary + ", Access-Control-Request-Private-Network",
+			},
+		},
+		{
+			name:   "private network not requested",
+			config: &Config{AccessControlAllowPrivateNetwork: true},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Private-Network": "",
+				"Vary":                                 preflightVary + ", Access-Control-Request-Private-Network",
+			},
+		},
+		{
+			name:   "private network not enabled",
+			config: nil,
+			headers: map[string]string{
+				"Origin":                                 "https://api.abc.com",
+				"Access-Control-Request-Method":          "GET",
+				"Access-Control-Request-Private-Network": "true",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Private-Network": "",
+				"Vary":                                 preflightVary,
+			},
+		},
+		{
+			name:   "max age disabled",
+			config: &Config{AccessControlMaxAge: "0"},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Max-Age": "0",
+			},
+		},
+		{
+			name: "non blocking leaves the response to the browser",
+			config: &Config{
+				AccessControlAllowOrigin: []string{"https://api.abc.com"},
+				NonBlocking:              true,
+			},
+			headers: map[string]string{
+				"Origin":                        "https://evil.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantReason:  ReasonOrigin,
+			wantCode:    http.StatusNoContent,
+			wantStopped: true,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "",
+			},
+		},
+		{
+			name:   "options passthrough",
+			config: &Config{OptionsPassthrough: true},
+			headers: map[string]string{
+				"Origin":                        "https://api.abc.com",
+				"Access-Control-Request-Method": "GET",
+			},
+			wantCode:    0,
+			wantStopped: false,
+			wantHeaders: map[string]string{
+				"Access-Control-Allow-Origin": "*",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{}
+			if tt.config != nil {
+				*config = *tt.config
+			}
+
+			var decision *Decision
+			config.OnDecision = func(ctx zeroapi.Context, d *Decision) {
+				decision = d
+			}
+
+			p, err := NewPolicy(config)
+			if err != nil {
+				t.Fatalf("NewPolicy() error = %v", err)
+			}
+
+			ctx := newTestContext(http.MethodOptions, tt.headers)
+			p.Handler()(ctx)
+
+			if decision == nil || !decision.Preflight {
+				t.Fatalf("decision = %+v, want preflight", decision)
+			}
+
+			if decision.Reason != tt.wantReason || decision.Allowed != (tt.wantReason == ReasonNone) {
+				t.Errorf("reason = %q, allowed = %v, want %q", decision.Reason, decision.Allowed, tt.wantReason)
+			}
+
+			if ctx.code != tt.wantCode {
+				t.Errorf("code = %d, want %d", ctx.code, tt.wantCode)
+			}
+
+			if ctx.stopped != tt.wantStopped {
+				t.Errorf("stopped = %v, want %v", ctx.stopped, tt.wantStopped)
+			}
+
+			for name, want := range tt.wantHeaders {
+				got, ok := ctx.response[http.CanonicalHeaderKey(name)]
+				if want == "" {
+					if ok {
+						t.Errorf("%s = %q, want absent", name, got)
+					}
+					continue
+				}
+
+				if len(got) != 1 || got[0] != want {
+					t.Errorf("%s = %q, want %q", name, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNotPreflight(t *testing.T) {
+	p, err := NewPolicy(nil)
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	// 没有 Origin 的请求不是跨域请求
+	ctx := newTestContext(http.MethodOptions, map[string]string{
+		"Access-Control-Request-Method": "GET",
+	})
+	p.Handler()(ctx)
+
+	if ctx.stopped || ctx.code != 0 {
+		t.Errorf("stopped = %v, code = %d, want passthrough", ctx.stopped, ctx.code)
+	}
+
+	if got := ctx.response.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Access-Control-Allow-Origin = %q, want absent", got)
+	}
+}
+
+func TestValidateOrigin(t *testing.T) {
+	tests := []struct {
+		origin string
+		valid  bool
+	}{
+		{origin: "*", valid: true},
+		{origin: "https://api.abc.com", valid: true},
+		{origin: "http://localhost:8080", valid: true},
+		{origin: "https://*.abc.com", valid: true},
+		{origin: "*.abc.com", valid: true},
+		{origin: "https://app?.abc.com", valid: true},
+		{origin: "app?.abc.com", valid: true},
+		{origin: "api.abc.com", valid: false},
+		{origin: "https://", valid: false},
+		{origin: "https://api.abc.com/path", valid: false},
+		{origin: "https://user@api.abc.com", valid: false},
+		{origin: "https://api.abc.com#top", valid: false},
+		{origin: "1https://api.abc.com", valid: false},
+		{origin: "", valid: false},
+	}
+
+	for _, tt := range tests {
+		err := (&Config{AccessControlAllowOrigin: []string{tt.origin}}).Validate()
+		if (err == nil) != tt.valid {
+			t.Errorf("Validate(%q) error = %v, want valid %v", tt.origin, err, tt.valid)
+		}
+	}
+}
+
+func TestAllowMethodsNormalized(t *testing.T) {
+	p, err := NewPolicy(&Config{AccessControlAllowMethods: []string{"get", "Post"}})
+	if err != nil {
+		t.Fatalf("NewPolicy() error = %v", err)
+	}
+
+	ctx := newTestContext(http.MethodOptions, map[string]string{
+		"Origin":                        "https://api.abc.com",
+		"Access-Control-Request-Method": "GET",
+	})
+	p.Handler()(ctx)
+
+	if ctx.code != http.StatusNoContent {
+		t.Errorf("code = %d, want %d", ctx.code, http.StatusNoContent)
+	}
+
+	if got := ctx.response.Get("Access-Control-Allow-Methods"); got != "GET,POST" {
+		t.Errorf("Access-Control-Allow-Methods = %q, want %q", got, "GET,POST")
+	}
+}
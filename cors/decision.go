@@ -0,0 +1,114 @@
+package cors
+
+import (
+	"sync/atomic"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Reason 检查未通过的规则
+type Reason string
+
+const (
+	// ReasonNone 检查通过
+	ReasonNone Reason = ""
+	// ReasonOrigin 来源不允许
+	ReasonOrigin Reason = "origin"
+	// ReasonMethod 方法不允许
+	ReasonMethod Reason = "method"
+	// ReasonHeader 请求头不允许
+	ReasonHeader Reason = "header"
+)
+
+// Decision 一次跨域检查的结果
+type Decision struct {
+	// Preflight 是否为预检请求
+	Preflight bool
+
+	// Origin 请求的来源
+	Origin string
+
+	// Method 预检请求时为 Access-Control-Request-Method，否则为请求方法
+	Method string
+
+	// Headers 预检请求时为 Access-Control-Request-Headers 的规范化形式，否则为空
+	Headers []string
+
+	// Allowed 是否通过检查
+	Allowed bool
+
+	// Reason 未通过的规则，通过时为 ReasonNone
+	Reason Reason
+}
+
+// Stats 检查结果的计数
+type Stats struct {
+	// PreflightAllowed 通过的预检请求个数
+	PreflightAllowed uint64
+	// PreflightDenied 未通过的预检请求个数
+	PreflightDenied uint64
+	// RequestAllowed 通过的正常请求个数
+	RequestAllowed uint64
+	// RequestDenied 未通过的正常请求个数
+	RequestDenied uint64
+}
+
+type stats struct {
+	preflightAllowed atomic.Uint64
+	preflightDenied  atomic.Uint64
+	requestAllowed   atomic.Uint64
+	requestDenied    atomic.Uint64
+}
+
+// Stats 获取检查结果的计数，可供监控中间件采集
+func (p *Policy) Stats() Stats {
+	return Stats{
+		PreflightAllowed: p.stats.preflightAllowed.Load(),
+		PreflightDenied:  p.stats.preflightDenied.Load(),
+		RequestAllowed:   p.stats.requestAllowed.Load(),
+		RequestDenied:    p.stats.requestDenied.Load(),
+	}
+}
+
+// report 记录检查结果: 计数，调用 OnDecision，调试模式下设置 X-Cors-Debug
+func (p *Policy) report(ctx zeroapi.Context, preflight bool, reason Reason) {
+	allowed := reason == ReasonNone
+
+	switch {
+	case preflight && allowed:
+		p.stats.preflightAllowed.Add(1)
+	case preflight:
+		p.stats.preflightDenied.Add(1)
+	case allowed:
+		p.stats.requestAllowed.Add(1)
+	default:
+		p.stats.requestDenied.Add(1)
+	}
+
+	if p.c.Debug {
+		if allowed {
+			ctx.SetHeader("X-Cors-Debug", "allowed")
+		} else {
+			ctx.SetHeader("X-Cors-Debug", "denied: "+string(reason))
+		}
+	}
+
+	if p.c.OnDecision == nil {
+		return
+	}
+
+	decision := &Decision{
+		Preflight: preflight,
+		Origin:    ctx.Header("Origin"),
+		Method:    ctx.Method(),
+		Allowed:   allowed,
+		Reason:    reason,
+	}
+
+	if preflight {
+		decision.Method = ctx.Header("Access-Control-Request-Method")
+		decision.Headers = requestHeaders(ctx)
+	}
+
+	p.c.OnDecision(ctx, decision)
+}
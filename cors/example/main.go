@@ -19,9 +19,50 @@ func main() {
 	a.Get("/", helloworldHandle)
 
 	// a.Use(zamcors.New(&zamcors.Config{
-	// 	AccessControlAllowOrigin: []string{"*.abc.com", "abc.com"},
+	// 	AccessControlAllowOrigin: []string{"*.abc.com", "https://abc.com"},
 	// }))
 
+	// 非阻塞模式，来源不允许时不返回 403，由浏览器拦截；预检请求继续交给 OPTIONS 处理函数
+	// a.Use(zamcors.New(&zamcors.Config{
+	// 	AccessControlAllowOrigin: []string{"*.abc.com", "https://abc.com"},
+	// 	NonBlocking:              true,
+	// 	OptionsPassthrough:       true,
+	// }))
+
+	// 按路由组使用不同的跨域策略，并通过 AllowOriginFunc 动态检查来源
+	// admin, err := zamcors.NewPolicy(&zamcors.Config{
+	// 	AccessControlAllowOrigin: []string{"https://admin.abc.com"},
+	// 	AllowOriginFunc: func(ctx zeroapi.Context, origin string) bool {
+	// 		return origin == "https://tenant.abc.com"
+	// 	},
+	// })
+	// if err != nil {
+	// 	a.Logger().Errorf("invalid cors policy, err: %s", err.Error())
+	// 	return
+	// }
+	// fallback, _ := zamcors.NewPolicy(nil)
+	// registry := zamcors.NewRegistry(fallback)
+	// registry.Register("/admin", admin)
+	// a.Use(registry.Handler())
+	//
+	// 运行时更新允许的来源
+	// if err := admin.SetAllowOrigin([]string{"https://admin.abc.com", "https://*.admin.abc.com"}); err != nil {
+	// 	a.Logger().Errorf("reload origin failed, err: %s", err.Error())
+	// }
+
+	// 记录检查结果，开发环境中返回 X-Cors-Debug
+	// policy, _ := zamcors.NewPolicy(&zamcors.Config{
+	// 	AccessControlAllowOrigin: []string{"*.abc.com"},
+	// 	Debug:                    true,
+	// 	OnDecision: func(ctx zeroapi.Context, decision *zamcors.Decision) {
+	// 		if !decision.Allowed {
+	// 			ctx.App().Logger().Warnf("cors denied, origin: %s, reason: %s", decision.Origin, decision.Reason)
+	// 		}
+	// 	},
+	// })
+	// a.Use(policy.Handler())
+	// 计数: policy.Stats()
+
 	// 跨域
 	a.Use(zamcors.New(nil))
 
@@ -34,24 +75,24 @@ func main() {
 }
 
 // 预检通过
-// 命令: curl -i -X OPTIONS -H "Origin:api.abc.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
+// 命令: curl -i -X OPTIONS -H "Origin:https://api.abc.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
 // 返回:
 // HTTP/1.1 204 No Content
 // Access-Control-Allow-Methods: HEAD,GET,POST,PUT,PATCH,DELETE
-// Access-Control-Allow-Origin: api.abc.com
+// Access-Control-Allow-Origin: https://api.abc.com
 // Access-Control-Max-Age: 600
 //
 // 预检未通过
-// 命令: curl -i -X OPTIONS -H "Origin:test.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
+// 命令: curl -i -X OPTIONS -H "Origin:https://test.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
 // 返回:
 // HTTP/1.1 403 Forbidden
 // Content-Length: 0
 //
 // 正常请求通过
-// 命令: curl -i -X GET -H "Origin:api.abc.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
+// 命令: curl -i -X GET -H "Origin:https://api.abc.com" -H "Access-Control-Request-Method:GET" http://127.0.0.1:8877
 // 返回:
 // HTTP/1.1 200 OK
-// Access-Control-Allow-Origin: api.abc.com
+// Access-Control-Allow-Origin: https://api.abc.com
 // Content-Length: 74
 // Content-Type: text/plain; charset=utf-8
 //
@@ -0,0 +1,102 @@
+package cors
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Registry 按路径前缀(路由组)注册不同的跨域策略
+//
+// 前缀按路径段匹配，"/api" 匹配 "/api" 与 "/api/users"，不匹配 "/apix"
+// 请求使用前缀最长的匹配策略，均不匹配时使用默认策略，默认策略为 nil 时不做跨域处理
+type Registry struct {
+	lock *sync.RWMutex
+
+	// routes 按前缀长度从长到短排序
+	routes []*route
+
+	// fallback 默认策略
+	fallback *Policy
+}
+
+type route struct {
+	prefix string
+	policy *Policy
+}
+
+// NewRegistry 创建跨域策略注册表
+//
+// fallback 默认策略，可以为 nil
+func NewRegistry(fallback *Policy) *Registry {
+	return &Registry{
+		lock:     &sync.RWMutex{},
+		routes:   make([]*route, 0),
+		fallback: fallback,
+	}
+}
+
+// Register 为路径前缀注册跨域策略，已存在时覆盖
+//
+// policy 使用 NewPolicy 创建
+func (r *Registry) Register(prefix string, policy *Policy) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for _, rt := range r.routes {
+		if rt.prefix == prefix {
+			rt.policy = policy
+			return
+		}
+	}
+
+	r.routes = append(r.routes, &route{prefix: prefix, policy: policy})
+	sort.SliceStable(r.routes, func(i, j int) bool {
+		return len(r.routes[i].prefix) > len(r.routes[j].prefix)
+	})
+}
+
+// Remove 移除路径前缀对应的跨域策略
+func (r *Registry) Remove(prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	for i, rt := range r.routes {
+		if rt.prefix == prefix {
+			r.routes = append(r.routes[:i], r.routes[i+1:]...)
+			return
+		}
+	}
+}
+
+// Policy 获取路径对应的跨域策略
+func (r *Registry) Policy(path string) *Policy {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	for _, rt := range r.routes {
+		if path == rt.prefix || strings.HasPrefix(path, rt.prefix+"/") {
+			return rt.policy
+		}
+	}
+
+	return r.fallback
+}
+
+// Handler 返回按路径选择跨域策略的中间件
+func (r *Registry) Handler() zeroapi.Handler {
+	return func(ctx zeroapi.Context) {
+		policy := r.Policy(ctx.Path())
+		if policy == nil {
+			return
+		}
+
+		policy.handle(ctx)
+	}
+}
@@ -0,0 +1,121 @@
+package cors
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrCredentialsWithAllOrigin 允许携带 Cookie 时，来源不能为 "*"
+	ErrCredentialsWithAllOrigin = errors.New(`AccessControlAllowCredentials can not be used with AccessControlAllowOrigin "*"`)
+)
+
+// Validate 检查配置是否有效，空值视为使用默认设置
+//
+// 1 AccessControlAllowCredentials 为 true 时，AccessControlAllowOrigin 不能含有 "*"
+// 2 AccessControlAllowOrigin 的格式为 scheme://host[:port]，不能含有路径、参数等
+// 含有通配符 "*" 或 "?" 时可以省略 scheme，如 "*.abc.com" 匹配任意 scheme 的子域名
+// 3 AccessControlAllowMethods 与 AccessControlAllowHeaders 不能为空字符串，也不能含有空白或 ","
+// 4 AccessControlMaxAge 必须为非负整数
+func (c *Config) Validate() error {
+	if err := validateOrigin(c.AccessControlAllowOrigin, c.AccessControlAllowCredentials); err != nil {
+		return err
+	}
+
+	for _, method := range c.AccessControlAllowMethods {
+		if !validToken(method) {
+			return fmt.Errorf("invalid AccessControlAllowMethods: %q", method)
+		}
+	}
+
+	for _, header := range c.AccessControlAllowHeaders {
+		if !validToken(header) {
+			return fmt.Errorf("invalid AccessControlAllowHeaders: %q", header)
+		}
+	}
+
+	for _, header := range c.AccessControlExposeHeaders {
+		if !validToken(header) {
+			return fmt.Errorf("invalid AccessControlExposeHeaders: %q", header)
+		}
+	}
+
+	if c.AccessControlMaxAge != "" {
+		maxAge, err := strconv.Atoi(c.AccessControlMaxAge)
+		if err != nil || maxAge < 0 {
+			return fmt.Errorf("invalid AccessControlMaxAge: %q, must be a non-negative integer", c.AccessControlMaxAge)
+		}
+	}
+
+	return nil
+}
+
+// validateOrigin 检查来源
+func validateOrigin(origins []string, credentials bool) error {
+	for _, origin := range origins {
+		if origin == "*" {
+			if credentials {
+				return ErrCredentialsWithAllOrigin
+			}
+			continue
+		}
+
+		if !validOrigin(origin) {
+			return fmt.Errorf("invalid AccessControlAllowOrigin: %q, must be scheme://host[:port]", origin)
+		}
+	}
+
+	return nil
+}
+
+// validOrigin 来源格式为 scheme://host[:port]，含有通配符时可以省略 scheme
+//
+// 浏览器发送的 Origin 总是带有 scheme，没有 scheme 且没有通配符的来源永远不会匹配
+func validOrigin(origin string) bool {
+	if len(origin) == 0 {
+		return false
+	}
+
+	host := origin
+	if i := strings.Index(origin, "://"); i >= 0 {
+		scheme := origin[:i]
+		if !validScheme(scheme) {
+			return false
+		}
+		host = origin[i+3:]
+	} else if !strings.ContainsAny(origin, "*?") {
+		return false
+	}
+
+	if len(host) == 0 {
+		return false
+	}
+
+	// 不能含有路径、锚点、用户信息以及空白，"?" 为通配符，可以使用
+	return !strings.ContainsAny(host, "/#@ \t\r\n")
+}
+
+// validScheme scheme = ALPHA *( ALPHA / DIGIT / "+" / "-" / "." )
+func validScheme(scheme string) bool {
+	if len(scheme) == 0 {
+		return false
+	}
+
+	for i, r := range scheme {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '+' || r == '-' || r == '.'):
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// validToken 不能为空，也不能含有空白或 ","
+func validToken(s string) bool {
+	return len(s) > 0 && !strings.ContainsAny(s, ", \t\r\n")
+}
@@ -9,6 +9,9 @@ import (
 
 	zerocrypto "github.com/zerogo-hub/zero-helper/crypto"
 	zerorandom "github.com/zerogo-hub/zero-helper/random"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 为请求填充 csrf
@@ -52,7 +55,7 @@ func Verify(opts ...Option) zeroapi.Handler {
 				if !opt.verify(ctx) {
 					ctx.Stopped()
 					ctx.SetHTTPCode(http.StatusBadRequest)
-					ctx.App().Logger().Errorf("invalid csrf token, method: %s, path: %s, ip: %s", ctx.Method(), ctx.Path(), ctx.IP())
+					ctx.App().Logger().Errorf("invalid csrf token, method: %s, path: %s, ip: %s, request_id: %s", ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 				}
 				break
 			}
@@ -8,6 +8,8 @@ import (
 
 	zeroapi "github.com/zerogo-hub/zero-api"
 	zerojwt "github.com/zerogo-hub/zero-helper/jwt"
+
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // TokenHandler 获取 jwt token 值
@@ -92,7 +94,7 @@ func TokenFromParam(ctx zeroapi.Context, param string) (string, error) {
 
 // OnFailed 失败时的回调
 func OnFailed(ctx zeroapi.Context, err error) {
-	ctx.App().Logger().Errorf("jwt check failed: %v", err)
+	ctx.App().Logger().Errorf("jwt check failed: %v, request_id: %s", err, requestid.Get(ctx))
 	ctx.SetHTTPCode(http.StatusUnauthorized)
 	ctx.Stopped()
 }
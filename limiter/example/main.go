@@ -22,7 +22,40 @@ func main() {
 	a.Get("/", helloworldHandle)
 
 	// 10 秒内允许 2 个请求
-	a.Use(zamlimiter.New(zamtime.Second(10), 2))
+	l := zamlimiter.NewLimiter(zamtime.Second(10), 2)
+	a.Use(l.Handle)
+
+	// 查看与修改限流配置，应注册在受保护的路由上
+	// 修改: curl -i -X POST "http://127.0.0.1:8877/admin/limiter?every=1s&burst=10"
+	a.Get("/admin/limiter", l.AdminHandler())
+	a.Post("/admin/limiter", l.AdminHandler())
+
+	// 针对 ip 限流，最多保存 10000 个 ip，空闲 5 分钟后清理
+	// store := zamlimiter.NewStore(&zamlimiter.StoreConfig{
+	// 	MaxKeys: 10000,
+	// 	IdleTTL: zamtime.Minute(5),
+	// })
+	// defer store.Stop()
+	// a.Use(zamlimiter.NewIP(zamtime.Second(10), 2, zamlimiter.Option{Store: store}))
+
+	// 按 API Key 限流，付费用户每秒 100 个请求，其他用户每秒 1 个请求
+	// 没有 API Key 的请求按 ip 限流，每 10 秒 1 个请求
+	// a.Use(zamlimiter.NewKeyed(&zamlimiter.KeyedConfig{
+	// 	KeyFunc: zamlimiter.KeyByHeader("X-Api-Key"),
+	// 	Rule:    zamlimiter.Rule{Every: zamtime.Second(1), Burst: 1},
+	// 	Rules: map[string]zamlimiter.Rule{
+	// 		"premium-api-key": {Every: zamtime.Millisecond(10), Burst: 100},
+	// 	},
+	// 	AnonymousRule: &zamlimiter.Rule{Every: zamtime.Second(10), Burst: 1},
+	// }))
+
+	// 等待模式，最多等待 3 秒，无法在 3 秒内获得令牌时返回 429 及 Retry-After
+	// a.Use(zamlimiter.New(zamtime.Second(1), 2, zamlimiter.Option{
+	// 	Wait:       true,
+	// 	MaxWait:    zamtime.Second(3),
+	// 	MaxQueue:   50,
+	// 	RejectCode: http.StatusTooManyRequests,
+	// }))
 
 	// 监听信号，比如优雅关闭
 	a.Server().HTTPServer().ListenSignal()
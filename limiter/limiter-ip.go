@@ -2,75 +2,51 @@ package limiter
 
 import (
 	"net/http"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // NewIP 针对 ip 的限流器，每隔 every 时间放入一个令牌，满 burst 个令牌后不放入新令牌
-func NewIP(every time.Duration, burst int) zeroapi.Handler {
+//
+// opts 可选，Option.Store 用于控制保存的 ip 个数、空闲清理以及停止后台清理
+func NewIP(every time.Duration, burst int, opts ...Option) zeroapi.Handler {
+	opt := defaultOption()
+	if len(opts) > 0 {
+		opt.replace(opts[0])
+	}
+
+	s := opt.Store
+	if s == nil {
+		s = newLazyStore()
+	}
+
+	r := rate.Every(every)
 
-	i := newIPRateLimiter(rate.Every(every), burst)
+	// waiting 所有 ip 正在等待令牌的请求个数
+	waiting := &atomic.Int64{}
 
 	return func(ctx zeroapi.Context) {
 		if ctx.Method() == http.MethodOptions {
 			return
 		}
 
-		ipStr := ctx.IP()
-		l := i.getLimiter(ipStr)
+		ipStr := realip.IP(ctx)
+		l := s.Get(ipStr, r, burst)
+
+		ok, delay := opt.take(ctx, l, waiting)
+		opt.writeHeader(ctx, l, ok, delay)
 
-		if !l.Allow() {
-			ctx.Stopped()
-			ctx.SetHTTPCode(http.StatusForbidden)
-			ctx.App().Logger().Errorf("ip limiter: %s, method: %s, path: %s, ip: %s", ipStr, ctx.Method(), ctx.Path(), ctx.IP())
+		if !ok {
+			opt.reject(ctx, delay)
+			ctx.App().Logger().Errorf("ip limiter: %s, method: %s, path: %s, ip: %s, request_id: %s", ipStr, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 			return
 		}
 	}
 }
-
-type ipRateLimter struct {
-	ips   map[string]*rate.Limiter
-	lock  *sync.RWMutex
-	r     rate.Limit
-	burst int
-}
-
-func newIPRateLimiter(r rate.Limit, burst int) *ipRateLimter {
-	limiter := &ipRateLimter{
-		ips:   make(map[string]*rate.Limiter),
-		lock:  &sync.RWMutex{},
-		r:     r,
-		burst: burst,
-	}
-
-	return limiter
-}
-
-func (limiter *ipRateLimter) addIP(ipStr string) *rate.Limiter {
-	limiter.lock.Lock()
-	defer limiter.lock.Unlock()
-
-	l := rate.NewLimiter(limiter.r, limiter.burst)
-	limiter.ips[ipStr] = l
-
-	return l
-}
-
-func (limiter *ipRateLimter) getLimiter(ipStr string) *rate.Limiter {
-	limiter.lock.RLock()
-
-	l, exists := limiter.ips[ipStr]
-
-	if !exists {
-		limiter.lock.RUnlock()
-		return limiter.addIP(ipStr)
-	}
-
-	limiter.lock.RUnlock()
-
-	return l
-}
@@ -0,0 +1,221 @@
+package limiter
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// KeyFunc 为请求生成限流使用的 key，返回空字符串时视为匿名请求
+type KeyFunc func(ctx zeroapi.Context) string
+
+// Rule 限流规则，每隔 Every 时间放入一个令牌，满 Burst 个令牌后不放入新令牌
+type Rule struct {
+	Every time.Duration
+	Burst int
+}
+
+// KeyedConfig 按 key 限流的配置
+type KeyedConfig struct {
+	// KeyFunc 生成 key，必须
+	KeyFunc KeyFunc
+
+	// Rule 默认规则
+	Rule Rule
+
+	// Rules 针对指定 key 的规则，优先于 RuleFunc 与 Rule
+	Rules map[string]Rule
+
+	// RuleFunc 动态选择规则，如根据套餐区分付费用户与匿名用户，返回 false 时使用默认规则
+	// NewKeyed 会拷贝 Rule、Rules 与 AnonymousRule，之后修改不生效，运行时修改规则只能通过 RuleFunc
+	RuleFunc func(ctx zeroapi.Context, key string) (Rule, bool)
+
+	// Anonymous KeyFunc 返回空字符串时，为匿名请求生成 key，默认 KeyByIP
+	//
+	// 在存储中，KeyFunc 生成的 key 添加 "key:" 前缀，匿名请求的 key 添加 "anonymous:" 前缀
+	// 客户端无法通过伪造 KeyFunc 使用的值，如 "X-Api-Key: anonymous:1.2.3.4"，消耗匿名请求的令牌
+	Anonymous KeyFunc
+
+	// AnonymousRule 匿名请求使用的规则，为 nil 时使用 Rule
+	AnonymousRule *Rule
+
+	// Option 等待模式、拒绝时的 HTTP Code 以及存储
+	Option Option
+}
+
+// NewKeyed 按 key 限流，key 可以是用户、API Key、路由、租户等
+//
+// 使用 config 的拷贝，之后修改 config 不影响已创建的限流器
+func NewKeyed(config *KeyedConfig) zeroapi.Handler {
+	if config == nil || config.KeyFunc == nil {
+		panic("KeyFunc cant be nil")
+	}
+
+	config = config.clone()
+
+	if config.Rule.Every <= 0 || config.Rule.Burst <= 0 {
+		panic("Rule.Every and Rule.Burst must bigger than zero")
+	}
+
+	if config.AnonymousRule != nil && (config.AnonymousRule.Every <= 0 || config.AnonymousRule.Burst <= 0) {
+		panic("AnonymousRule.Every and AnonymousRule.Burst must bigger than zero")
+	}
+
+	anonymous := config.Anonymous
+	if anonymous == nil {
+		anonymous = KeyByIP()
+	}
+
+	opt := defaultOption()
+	opt.replace(config.Option)
+
+	s := opt.Store
+	if s == nil {
+		s = newLazyStore()
+	}
+
+	// waiting 所有 key 正在等待令牌的请求个数
+	waiting := &atomic.Int64{}
+
+	return func(ctx zeroapi.Context) {
+		if ctx.Method() == http.MethodOptions {
+			return
+		}
+
+		var rule Rule
+		key := config.KeyFunc(ctx)
+		if len(key) == 0 {
+			key = "anonymous:" + anonymous(ctx)
+			rule = config.Rule
+			if config.AnonymousRule != nil {
+				rule = *config.AnonymousRule
+			}
+		} else {
+			rule = config.rule(ctx, key)
+			key = "key:" + key
+		}
+
+		l := s.Get(key, rate.Every(rule.Every), rule.Burst)
+
+		ok, delay := opt.take(ctx, l, waiting)
+		opt.writeHeader(ctx, l, ok, delay)
+
+		if !ok {
+			opt.reject(ctx, delay)
+			ctx.App().Logger().Errorf("keyed limiter: %s, method: %s, path: %s, ip: %s, request_id: %s", key, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
+			return
+		}
+	}
+}
+
+// clone 拷贝配置，避免调用方在运行时修改 Rules 导致并发读写 map
+func (config *KeyedConfig) clone() *KeyedConfig {
+	c := *config
+
+	if config.Rules != nil {
+		c.Rules = make(map[string]Rule, len(config.Rules))
+		for key, rule := range config.Rules {
+			c.Rules[key] = rule
+		}
+	}
+
+	if config.AnonymousRule != nil {
+		rule := *config.AnonymousRule
+		c.AnonymousRule = &rule
+	}
+
+	return &c
+}
+
+// rule 选择 key 使用的规则
+func (config *KeyedConfig) rule(ctx zeroapi.Context, key string) Rule {
+	if rule, ok := config.Rules[key]; ok {
+		return rule
+	}
+
+	if config.RuleFunc != nil {
+		if rule, ok := config.RuleFunc(ctx, key); ok {
+			return rule
+		}
+	}
+
+	return config.Rule
+}
+
+// KeyByIP 使用 ip 作为 key
+func KeyByIP() KeyFunc {
+	return func(ctx zeroapi.Context) string {
+		return realip.IP(ctx)
+	}
+}
+
+// KeyByHeader 使用请求头作为 key，如 API Key、租户 ID
+func KeyByHeader(name string) KeyFunc {
+	return func(ctx zeroapi.Context) string {
+		return ctx.Header(name)
+	}
+}
+
+// KeyByValue 使用上下文中的值作为 key，如 jwt 中间件写入的用户标识
+func KeyByValue(name string) KeyFunc {
+	return func(ctx zeroapi.Context) string {
+		v := ctx.Value(name)
+		if v == nil {
+			return ""
+		}
+
+		if s, ok := v.(string); ok {
+			return s
+		}
+
+		return fmt.Sprint(v)
+	}
+}
+
+// KeyByRoute 使用请求方法与路由模板作为 key，如 "GET /users/:id"
+//
+// route 返回请求匹配的路由模板，与 logger.Config.Route 相同，不使用原始路径，避免 key 的个数随路径参数无限增长
+func KeyByRoute(route func(ctx zeroapi.Context) string) KeyFunc {
+	if route == nil {
+		panic("route cant be nil")
+	}
+
+	return func(ctx zeroapi.Context) string {
+		r := route(ctx)
+		if len(r) == 0 {
+			return ""
+		}
+
+		return ctx.Method() + " " + r
+	}
+}
+
+// KeyJoin 组合多个 key，使用 ":" 连接，任意一个为空时返回空字符串
+//
+// 如 KeyJoin(KeyByValue("uid"), KeyByRoute(route)) 对每一个用户的每一个路由限流
+func KeyJoin(fns ...KeyFunc) KeyFunc {
+	return func(ctx zeroapi.Context) string {
+		key := ""
+		for i, fn := range fns {
+			k := fn(ctx)
+			if len(k) == 0 {
+				return ""
+			}
+
+			if i > 0 {
+				key += ":"
+			}
+			key += k
+		}
+
+		return key
+	}
+}
@@ -0,0 +1,139 @@
+package limiter
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zerologger "github.com/zerogo-hub/zero-helper/logger"
+)
+
+// testContext 只实现限流使用的方法
+type testContext struct {
+	zeroapi.Context
+
+	ip      string
+	request http.Header
+	code    int
+	stopped bool
+}
+
+func newTestContext(ip string, headers map[string]string) *testContext {
+	ctx := &testContext{ip: ip, request: http.Header{}}
+	for k, v := range headers {
+		ctx.request.Set(k, v)
+	}
+
+	return ctx
+}
+
+func (ctx *testContext) App() zeroapi.App             { return testApp{} }
+func (ctx *testContext) Method() string               { return http.MethodGet }
+func (ctx *testContext) Path() string                 { return "/" }
+func (ctx *testContext) IP() string                   { return ctx.ip }
+func (ctx *testContext) Header(key string) string     { return ctx.request.Get(key) }
+func (ctx *testContext) SetHeader(key, value string)  {}
+func (ctx *testContext) Stopped()                     { ctx.stopped = true }
+func (ctx *testContext) SetHTTPCode(code int)         { ctx.code = code }
+func (ctx *testContext) Value(key string) interface{} { return nil }
+
+type testApp struct {
+	zeroapi.App
+}
+
+func (testApp) Logger() zerologger.Logger { return testLogger{} }
+
+type testLogger struct {
+	zerologger.Logger
+}
+
+func (testLogger) Errorf(format string, v ...interface{}) {}
+
+func TestKeyedAnonymousCollision(t *testing.T) {
+	h := NewKeyed(&KeyedConfig{
+		KeyFunc: KeyByHeader("X-Api-Key"),
+		Rule:    Rule{Every: time.Hour, Burst: 1},
+	})
+
+	// 伪造的 API Key 与匿名请求的 key 相同
+	forged := newTestContext("5.6.7.8", map[string]string{"X-Api-Key": "anonymous:1.2.3.4"})
+	h(forged)
+	if forged.stopped {
+		t.Fatal("forged key limited, want allowed")
+	}
+
+	// 不受伪造请求的影响
+	anonymous := newTestContext("1.2.3.4", nil)
+	h(anonymous)
+	if anonymous.stopped {
+		t.Fatal("anonymous request limited by forged key, want allowed")
+	}
+
+	// 令牌已用完
+	again := newTestContext("1.2.3.4", nil)
+	h(again)
+	if !again.stopped || again.code != http.StatusForbidden {
+		t.Errorf("stopped = %v, code = %d, want limited", again.stopped, again.code)
+	}
+}
+
+func TestKeyedAnonymousRule(t *testing.T) {
+	h := NewKeyed(&KeyedConfig{
+		KeyFunc:       KeyByHeader("X-Api-Key"),
+		Rule:          Rule{Every: time.Hour, Burst: 2},
+		AnonymousRule: &Rule{Every: time.Hour, Burst: 1},
+	})
+
+	limited := 0
+	for i := 0; i < 3; i++ {
+		ctx := newTestContext("1.2.3.4", nil)
+		h(ctx)
+		if ctx.stopped {
+			limited++
+		}
+	}
+
+	if limited != 2 {
+		t.Errorf("anonymous limited = %d, want 2", limited)
+	}
+
+	limited = 0
+	for i := 0; i < 3; i++ {
+		ctx := newTestContext("1.2.3.4", map[string]string{"X-Api-Key": "k"})
+		h(ctx)
+		if ctx.stopped {
+			limited++
+		}
+	}
+
+	if limited != 1 {
+		t.Errorf("keyed limited = %d, want 1", limited)
+	}
+}
+
+func TestKeyedCopiesConfig(t *testing.T) {
+	config := &KeyedConfig{
+		KeyFunc: KeyByHeader("X-Api-Key"),
+		Rule:    Rule{Every: time.Hour, Burst: 1},
+		Rules:   map[string]Rule{"premium": {Every: time.Hour, Burst: 2}},
+	}
+	h := NewKeyed(config)
+
+	// 创建后修改 config 不生效
+	config.Rules["premium"] = Rule{Every: time.Hour, Burst: 100}
+	config.Rule.Burst = 100
+
+	limited := 0
+	for i := 0; i < 3; i++ {
+		ctx := newTestContext("1.2.3.4", map[string]string{"X-Api-Key": "premium"})
+		h(ctx)
+		if ctx.stopped {
+			limited++
+		}
+	}
+
+	if limited != 1 {
+		t.Errorf("limited = %d, want 1", limited)
+	}
+}
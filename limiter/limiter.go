@@ -1,51 +1,216 @@
+// Package limiter 限流
 package limiter
 
 import (
+	"encoding/json"
 	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"golang.org/x/time/rate"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
-var (
+// Limiter 全局限流器，每一个实例拥有独立的令牌桶
+//
+// 使用 NewLimiter 创建，a.Use(l.Handle) 注册为中间件
+type Limiter struct {
 	limiter *rate.Limiter
+	opt     Option
+
+	// waiting 正在等待令牌的请求个数
+	waiting atomic.Int64
+
+	// lock 保护 every
+	lock  *sync.RWMutex
+	every time.Duration
+
+	allowed  atomic.Uint64
+	rejected atomic.Uint64
+}
+
+// Stats 限流器统计
+type Stats struct {
+	// Every 每隔 Every 时间放入一个令牌
+	Every time.Duration `json:"every"`
+	// Burst 令牌桶大小
+	Burst int `json:"burst"`
+	// Tokens 当前可用的令牌个数
+	Tokens float64 `json:"tokens"`
+	// Allowed 通过的请求个数
+	Allowed uint64 `json:"allowed"`
+	// Rejected 被拒绝的请求个数
+	Rejected uint64 `json:"rejected"`
+	// Waiting 正在等待令牌的请求个数
+	Waiting int64 `json:"waiting"`
+}
+
+var (
+	// last 最近一次通过 New 创建的限流器，供已废弃的 SetLimit 与 SetBurst 使用
+	last atomic.Pointer[Limiter]
 )
 
 // New 全局限流器，每隔 every 时间放入一个令牌，满 burst 个令牌后不放入新令牌
-func New(every time.Duration, burst int) zeroapi.Handler {
+//
+// opts 可选，用于开启等待模式，设置拒绝时的 HTTP Code
+//
+// 为兼容保留，包级别的 SetLimit 与 SetBurst 修改最近一次通过 New 创建的限流器
+// 新代码使用 NewLimiter，返回的 Limiter 可以在运行时查看或者修改限流配置
+func New(every time.Duration, burst int, opts ...Option) zeroapi.Handler {
+	l := NewLimiter(every, burst, opts...)
+	last.Store(l)
+
+	return l.Handle
+}
+
+// NewLimiter 创建全局限流器，参数与 New 相同，不受包级别的 SetLimit 与 SetBurst 影响
+func NewLimiter(every time.Duration, burst int, opts ...Option) *Limiter {
+	opt := defaultOption()
+	if len(opts) > 0 {
+		opt.replace(opts[0])
+	}
 
 	// 每隔 every 时间放入 1 个，初始放入 burst 个
-	limiter = rate.NewLimiter(rate.Every(every), burst)
+	// 每秒 10 个，上限 100: rate.NewLimiter(rate.Limit(10), 100)
+	l := &Limiter{
+		limiter: rate.NewLimiter(rate.Every(every), burst),
+		opt:     opt,
+		lock:    &sync.RWMutex{},
+		every:   every,
+	}
 
-	// 每秒 10 个，上限 100
-	// limiter = rate.NewLimiter(rate.Limit(10), 100)
+	return l
+}
+
+// Handle 中间件
+func (l *Limiter) Handle(ctx zeroapi.Context) {
+	if ctx.Method() == http.MethodOptions {
+		return
+	}
+
+	ok, delay := l.opt.take(ctx, l.limiter, &l.waiting)
+	l.opt.writeHeader(ctx, l.limiter, ok, delay)
+
+	if !ok {
+		l.rejected.Add(1)
+		l.opt.reject(ctx, delay)
+		ctx.App().Logger().Errorf("global limiter, method: %s, path: %s, ip: %s, request_id: %s", ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
+		return
+	}
+
+	l.allowed.Add(1)
+}
 
+// SetLimit 动态修改放入令牌的速率
+func (l *Limiter) SetLimit(every time.Duration) {
+	l.lock.Lock()
+	l.every = every
+	l.lock.Unlock()
+
+	l.limiter.SetLimit(rate.Every(every))
+}
+
+// SetBurst 动态修改令牌桶大小
+func (l *Limiter) SetBurst(burst int) {
+	l.limiter.SetBurst(burst)
+}
+
+// Stats 获取限流器统计
+func (l *Limiter) Stats() Stats {
+	l.lock.RLock()
+	every := l.every
+	l.lock.RUnlock()
+
+	return Stats{
+		Every:    every,
+		Burst:    l.limiter.Burst(),
+		Tokens:   l.limiter.Tokens(),
+		Allowed:  l.allowed.Load(),
+		Rejected: l.rejected.Load(),
+		Waiting:  l.waiting.Load(),
+	}
+}
+
+// AdminHandler 查看与修改限流配置，需自行注册到受保护的路由上
+//
+// GET: 返回 Stats
+//
+// POST: 参数 every(如 "100ms", "1s") 与 burst，修改后返回 Stats
+func (l *Limiter) AdminHandler() zeroapi.Handler {
 	return func(ctx zeroapi.Context) {
-		if ctx.Method() == http.MethodOptions {
-			return
+		if ctx.Method() == http.MethodPost {
+			// 两个参数均检查通过后再修改，避免只修改其中一个
+			var every time.Duration
+			if s := ctx.Query("every"); len(s) > 0 {
+				d, err := time.ParseDuration(s)
+				if err != nil || d <= 0 {
+					badRequest(ctx, "invalid every")
+					return
+				}
+				every = d
+			}
+
+			burst := -1
+			if s := ctx.Query("burst"); len(s) > 0 {
+				n, err := strconv.Atoi(s)
+				if err != nil || n < 0 {
+					badRequest(ctx, "invalid burst")
+					return
+				}
+				burst = n
+			}
+
+			if every > 0 {
+				l.SetLimit(every)
+			}
+			if burst >= 0 {
+				l.SetBurst(burst)
+			}
+
+			ctx.App().Logger().Warnf("global limiter changed, every: %s, burst: %s, ip: %s, request_id: %s", ctx.Query("every"), ctx.Query("burst"), realip.IP(ctx), requestid.Get(ctx))
 		}
 
-		if !limiter.Allow() {
-			ctx.Stopped()
-			ctx.SetHTTPCode(http.StatusForbidden)
-			ctx.App().Logger().Errorf("global limiter, method: %s, path: %s, ip: %s", ctx.Method(), ctx.Path(), ctx.IP())
+		b, err := json.Marshal(l.Stats())
+		if err != nil {
+			ctx.SetHTTPCode(http.StatusInternalServerError)
+			ctx.App().Logger().Errorf("marshal limiter stats failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 			return
 		}
+
+		if _, err := ctx.Text(string(b)); err != nil {
+			ctx.App().Logger().Errorf("write limiter stats failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+		}
 	}
 }
 
-// SetLimit 动态修改放入令牌的速率
+// badRequest 参数无效
+func badRequest(ctx zeroapi.Context, message string) {
+	ctx.SetHTTPCode(http.StatusBadRequest)
+	if _, err := ctx.Message(http.StatusBadRequest, message); err != nil {
+		ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+	}
+}
+
+// SetLimit 动态修改最近一次通过 New 创建的限流器放入令牌的速率
+//
+// Deprecated: 使用 Limiter.SetLimit
 func SetLimit(every time.Duration) {
-	if limiter != nil {
-		limiter.SetLimit(rate.Every(every))
+	if l := last.Load(); l != nil {
+		l.SetLimit(every)
 	}
 }
 
-// SetBurst 动态修改令牌桶大小
+// SetBurst 动态修改最近一次通过 New 创建的限流器令牌桶大小
+//
+// Deprecated: 使用 Limiter.SetBurst
 func SetBurst(burst int) {
-	if limiter != nil {
-		limiter.SetBurst(burst)
+	if l := last.Load(); l != nil {
+		l.SetBurst(burst)
 	}
 }
@@ -0,0 +1,17 @@
+package limiter
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSetLimitOnlyAffectsNew(t *testing.T) {
+	New(time.Second, 1)
+	l := NewLimiter(time.Second, 1)
+
+	SetBurst(5)
+
+	if got := l.Stats().Burst; got != 1 {
+		t.Errorf("NewLimiter burst = %d, want 1", got)
+	}
+}
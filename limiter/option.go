@@ -0,0 +1,153 @@
+package limiter
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/ratelimit"
+)
+
+// Option ..
+type Option struct {
+	// Wait 没有令牌时是否等待，默认 false，立即拒绝
+	// 适用于内部的批处理接口
+	Wait bool
+
+	// MaxWait 最长等待时间，默认 1 秒，预计等待时间超过该值时立即拒绝
+	MaxWait time.Duration
+
+	// MaxQueue 最多同时等待的请求个数，默认 100，超出时立即拒绝
+	MaxQueue int64
+
+	// RejectCode 拒绝时的 HTTP Code，默认 403
+	// 为 429 时，根据需要等待的时间设置 Retry-After
+	RejectCode int
+
+	// Header 设置限流响应头，默认不设置，与 throttle 共用
+	// 设置后，Retry-After 也由 Header 决定
+	Header *ratelimit.Writer
+
+	// Store 保存每一个 key 的限流器，仅 NewIP 与 NewKeyed 使用
+	// 默认使用不启动后台清理的存储，由请求顺带清理空闲的 key
+	Store *Store
+}
+
+func defaultOption() Option {
+	return Option{
+		MaxWait:    time.Second,
+		MaxQueue:   100,
+		RejectCode: http.StatusForbidden,
+	}
+}
+
+func (opt *Option) replace(option Option) {
+	opt.Wait = option.Wait
+	if option.MaxWait > 0 {
+		opt.MaxWait = option.MaxWait
+	}
+	if option.MaxQueue > 0 {
+		opt.MaxQueue = option.MaxQueue
+	}
+	if option.RejectCode > 0 {
+		opt.RejectCode = option.RejectCode
+	}
+	if option.Header != nil {
+		opt.Header = option.Header
+	}
+	if option.Store != nil {
+		opt.Store = option.Store
+	}
+}
+
+// take 获取一个令牌，返回是否获取成功，失败时同时返回需要等待的时间
+//
+// waiting 为当前正在等待的请求个数
+func (opt *Option) take(ctx zeroapi.Context, l *rate.Limiter, waiting *atomic.Int64) (bool, time.Duration) {
+	// 使用 Reservation 而非 Allow，以便得到需要等待的时间
+	r := l.Reserve()
+	if !r.OK() {
+		return false, 0
+	}
+
+	delay := r.Delay()
+	if delay == 0 {
+		return true, 0
+	}
+
+	if !opt.Wait || delay > opt.MaxWait {
+		r.Cancel()
+		return false, delay
+	}
+
+	if waiting.Add(1) > opt.MaxQueue {
+		waiting.Add(-1)
+		r.Cancel()
+		return false, delay
+	}
+	defer waiting.Add(-1)
+
+	// 与 rate.Limiter.Wait 相同，等待期间请求被取消时归还令牌
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true, 0
+	case <-ctx.Request().Context().Done():
+		r.Cancel()
+		return false, 0
+	}
+}
+
+// writeHeader 设置限流响应头
+func (opt *Option) writeHeader(ctx zeroapi.Context, l *rate.Limiter, ok bool, delay time.Duration) {
+	if opt.Header == nil {
+		return
+	}
+
+	burst := l.Burst()
+	tokens := l.Tokens()
+	if tokens < 0 {
+		tokens = 0
+	}
+
+	// 令牌桶的配额为 burst 个，每隔 every 放入一个令牌
+	var every time.Duration
+	if limit := l.Limit(); limit > 0 && limit != rate.Inf {
+		every = time.Duration(float64(time.Second) / float64(limit))
+	}
+
+	result := ratelimit.Result{
+		Limited:    !ok,
+		Limit:      burst,
+		Remaining:  int(tokens),
+		Reset:      time.Duration((float64(burst) - tokens) * float64(every)),
+		RetryAfter: -1,
+		Quota:      burst,
+		Window:     every * time.Duration(burst),
+	}
+
+	if !ok {
+		result.RetryAfter = delay
+	}
+
+	opt.Header.Write(ctx, result)
+}
+
+// reject 拒绝请求
+func (opt *Option) reject(ctx zeroapi.Context, delay time.Duration) {
+	if opt.Header == nil && opt.RejectCode == http.StatusTooManyRequests && delay > 0 {
+		// 需要等待多长时间之后才能继续发送请求
+		ctx.SetHeader("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+
+	ctx.Stopped()
+	ctx.SetHTTPCode(opt.RejectCode)
+}
@@ -0,0 +1,252 @@
+package limiter
+
+import (
+	"container/list"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// StoreConfig 限流器存储配置
+type StoreConfig struct {
+	// Shards 分片个数，减少锁竞争，默认 16
+	Shards int
+
+	// MaxKeys 最多保存的 key 个数，超出时淘汰最久未使用的 key，默认 65536
+	MaxKeys int
+
+	// IdleTTL key 超过该时间未使用时被清理，默认 10 分钟
+	IdleTTL time.Duration
+
+	// JanitorInterval 后台清理的间隔，默认 1 分钟
+	// 小于 0 时不启动后台清理，由 Get 每隔 1 分钟清理所在分片中空闲的 key，无需调用 Stop
+	JanitorInterval time.Duration
+}
+
+// StoreStats 存储统计
+type StoreStats struct {
+	// Keys 当前保存的 key 个数
+	Keys int
+	// Evicted 因超出 MaxKeys 被淘汰的 key 个数
+	Evicted uint64
+	// Expired 因超过 IdleTTL 被清理的 key 个数
+	Expired uint64
+}
+
+// Store 分片存储每一个 key 的限流器，容量有限，按 LRU 与空闲时间淘汰
+type Store struct {
+	shards  []*shard
+	idleTTL time.Duration
+
+	// lazyInterval 不启动后台清理时，Get 清理分片的间隔
+	lazyInterval time.Duration
+
+	evicted atomic.Uint64
+	expired atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+type shard struct {
+	lock  sync.Mutex
+	max   int
+	items map[string]*list.Element
+	// lru 头部为最近使用
+	lru *list.List
+	// cleaned 最近一次由 Get 清理的时间
+	cleaned time.Time
+}
+
+type entry struct {
+	key      string
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+func defaultStoreConfig() *StoreConfig {
+	return &StoreConfig{
+		Shards:          16,
+		MaxKeys:         65536,
+		IdleTTL:         10 * time.Minute,
+		JanitorInterval: time.Minute,
+	}
+}
+
+// NewStore 创建限流器存储，并启动后台清理，不再使用时调用 Stop
+//
+// config 为 nil 时使用默认设置，JanitorInterval 小于 0 时不启动后台清理
+func NewStore(config *StoreConfig) *Store {
+	c := defaultStoreConfig()
+	if config != nil {
+		if config.Shards > 0 {
+			c.Shards = config.Shards
+		}
+		if config.MaxKeys > 0 {
+			c.MaxKeys = config.MaxKeys
+		}
+		if config.IdleTTL > 0 {
+			c.IdleTTL = config.IdleTTL
+		}
+		if config.JanitorInterval != 0 {
+			c.JanitorInterval = config.JanitorInterval
+		}
+	}
+
+	perShard := c.MaxKeys / c.Shards
+	if perShard < 1 {
+		perShard = 1
+	}
+
+	s := &Store{
+		shards:  make([]*shard, c.Shards),
+		idleTTL: c.IdleTTL,
+		stop:    make(chan struct{}),
+	}
+
+	now := time.Now()
+	for i := range s.shards {
+		s.shards[i] = &shard{
+			max:     perShard,
+			items:   make(map[string]*list.Element),
+			lru:     list.New(),
+			cleaned: now,
+		}
+	}
+
+	if c.JanitorInterval < 0 {
+		s.lazyInterval = time.Minute
+		return s
+	}
+
+	go s.janitor(c.JanitorInterval)
+
+	return s
+}
+
+// newLazyStore NewIP 与 NewKeyed 未指定 Option.Store 时使用，不启动后台清理，不会泄漏协程
+func newLazyStore() *Store {
+	return NewStore(&StoreConfig{JanitorInterval: -1})
+}
+
+// Get 获取 key 对应的限流器，不存在时按 r 与 burst 创建，已存在但规则不同时更新为 r 与 burst
+func (s *Store) Get(key string, r rate.Limit, burst int) *rate.Limiter {
+	sh := s.shard(key)
+	now := time.Now()
+
+	sh.lock.Lock()
+	defer sh.lock.Unlock()
+
+	if s.lazyInterval > 0 && now.Sub(sh.cleaned) >= s.lazyInterval {
+		sh.cleaned = now
+		s.expire(sh, now.Add(-s.idleTTL))
+	}
+
+	if el, ok := sh.items[key]; ok {
+		e := el.Value.(*entry)
+		e.lastSeen = now
+		sh.lru.MoveToFront(el)
+
+		// RuleFunc 为同一个 key 选择了新的规则
+		if e.limiter.Limit() != r {
+			e.limiter.SetLimitAt(now, r)
+		}
+		if e.limiter.Burst() != burst {
+			e.limiter.SetBurstAt(now, burst)
+		}
+
+		return e.limiter
+	}
+
+	if sh.lru.Len() >= sh.max {
+		if oldest := sh.lru.Back(); oldest != nil {
+			sh.remove(oldest)
+			s.evicted.Add(1)
+		}
+	}
+
+	e := &entry{key: key, limiter: rate.NewLimiter(r, burst), lastSeen: now}
+	sh.items[key] = sh.lru.PushFront(e)
+
+	return e.limiter
+}
+
+// Stats 获取存储统计
+func (s *Store) Stats() StoreStats {
+	keys := 0
+	for _, sh := range s.shards {
+		sh.lock.Lock()
+		keys += sh.lru.Len()
+		sh.lock.Unlock()
+	}
+
+	return StoreStats{
+		Keys:    keys,
+		Evicted: s.evicted.Load(),
+		Expired: s.expired.Load(),
+	}
+}
+
+// Stop 停止后台清理，可重复调用
+func (s *Store) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+func (s *Store) janitor(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.cleanup()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// cleanup 清理超过 idleTTL 未使用的 key
+func (s *Store) cleanup() {
+	deadline := time.Now().Add(-s.idleTTL)
+
+	for _, sh := range s.shards {
+		sh.lock.Lock()
+		s.expire(sh, deadline)
+		sh.lock.Unlock()
+	}
+}
+
+// expire 清理分片中 deadline 之前未使用的 key，调用时需持有分片的锁
+func (s *Store) expire(sh *shard, deadline time.Time) {
+	// 从尾部(最久未使用)开始清理
+	for el := sh.lru.Back(); el != nil; {
+		if el.Value.(*entry).lastSeen.After(deadline) {
+			break
+		}
+		prev := el.Prev()
+		sh.remove(el)
+		s.expired.Add(1)
+		el = prev
+	}
+}
+
+// shard 使用 FNV-1a 选择分片
+func (s *Store) shard(key string) *shard {
+	var h uint32 = 2166136261
+	for i := 0; i < len(key); i++ {
+		h ^= uint32(key[i])
+		h *= 16777619
+	}
+
+	return s.shards[h%uint32(len(s.shards))]
+}
+
+func (sh *shard) remove(el *list.Element) {
+	sh.lru.Remove(el)
+	delete(sh.items, el.Value.(*entry).key)
+}
@@ -0,0 +1,254 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrClosed AsyncWriter 已关闭
+var ErrClosed = errors.New("logger: async writer closed")
+
+// DropPolicy 缓冲区已满时的处理方式
+type DropPolicy int
+
+const (
+	// DropNewest 丢弃新的日志
+	DropNewest DropPolicy = iota
+	// DropOldest 丢弃最旧的日志
+	DropOldest
+	// Block 阻塞请求，直到缓冲区有空间
+	Block
+)
+
+// AsyncConfig 异步输出的配置
+type AsyncConfig struct {
+	// Size 缓冲区最多保存的日志条数，默认 8192
+	Size int
+
+	// BatchSize 每次最多合并写入的日志条数，缓冲的日志达到该值时立即写入，默认 256
+	BatchSize int
+
+	// FlushInterval 未达到 BatchSize 时，定时写入的间隔，默认 1s
+	FlushInterval time.Duration
+
+	// Policy 缓冲区已满时的处理方式，默认 DropNewest
+	Policy DropPolicy
+
+	// OnError 写入失败时调用，默认忽略
+	OnError func(err error)
+}
+
+// AsyncWriter 异步、批量写入日志，避免写日志的 io 增加请求的耗时
+//
+// 作为 Config.Output 使用，服务关闭前调用 Close，将缓冲的日志写入
+type AsyncWriter struct {
+	w      io.Writer
+	config *AsyncConfig
+
+	lock    sync.Mutex
+	notFull *sync.Cond
+	ring    [][]byte
+	head    int
+	n       int
+	closed  bool
+
+	dropped atomic.Uint64
+
+	notify chan struct{}
+	flush  chan chan struct{}
+	done   chan struct{}
+}
+
+// NewAsyncWriter 创建异步输出，日志最终写入 w
+func NewAsyncWriter(w io.Writer, config ...*AsyncConfig) *AsyncWriter {
+	c := &AsyncConfig{}
+	if len(config) > 0 && config[0] != nil {
+		*c = *config[0]
+	}
+
+	if c.Size <= 0 {
+		c.Size = 8192
+	}
+
+	if c.BatchSize <= 0 {
+		c.BatchSize = 256
+	}
+
+	if c.BatchSize > c.Size {
+		c.BatchSize = c.Size
+	}
+
+	if c.FlushInterval <= 0 {
+		c.FlushInterval = time.Second
+	}
+
+	a := &AsyncWriter{
+		w:      w,
+		config: c,
+		ring:   make([][]byte, c.Size),
+		notify: make(chan struct{}, 1),
+		flush:  make(chan chan struct{}),
+		done:   make(chan struct{}),
+	}
+	a.notFull = sync.NewCond(&a.lock)
+
+	go a.run()
+
+	return a
+}
+
+// Write 复制 p 并放入缓冲区，不等待写入完成
+//
+// 缓冲区已满时按 Policy 处理，丢弃的日志计入 Dropped，不返回错误
+func (a *AsyncWriter) Write(p []byte) (int, error) {
+	a.lock.Lock()
+
+	for a.n == len(a.ring) && !a.closed {
+		switch a.config.Policy {
+		case DropOldest:
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+			a.dropped.Add(1)
+		case Block:
+			a.notFull.Wait()
+		default:
+			a.lock.Unlock()
+			a.dropped.Add(1)
+			return len(p), nil
+		}
+	}
+
+	if a.closed {
+		a.lock.Unlock()
+		return 0, ErrClosed
+	}
+
+	a.ring[(a.head+a.n)%len(a.ring)] = append([]byte(nil), p...)
+	a.n++
+	full := a.n >= a.config.BatchSize
+
+	a.lock.Unlock()
+
+	if full {
+		select {
+		case a.notify <- struct{}{}:
+		default:
+		}
+	}
+
+	return len(p), nil
+}
+
+// Dropped 因缓冲区已满而丢弃的日志条数
+func (a *AsyncWriter) Dropped() uint64 {
+	return a.dropped.Load()
+}
+
+// Len 缓冲区中尚未写入的日志条数
+func (a *AsyncWriter) Len() int {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+
+	return a.n
+}
+
+// Flush 写入缓冲区中的所有日志，写入完成后返回
+func (a *AsyncWriter) Flush() error {
+	ch := make(chan struct{})
+
+	select {
+	case a.flush <- ch:
+		<-ch
+		return nil
+	case <-a.done:
+		return ErrClosed
+	}
+}
+
+// Close 拒绝新的日志，写入缓冲区中的所有日志后返回，不关闭 w
+func (a *AsyncWriter) Close() error {
+	a.lock.Lock()
+	if a.closed {
+		a.lock.Unlock()
+		return ErrClosed
+	}
+	a.closed = true
+	a.notFull.Broadcast()
+	a.lock.Unlock()
+
+	select {
+	case a.notify <- struct{}{}:
+	default:
+	}
+
+	<-a.done
+
+	return nil
+}
+
+// run 后台写入日志
+func (a *AsyncWriter) run() {
+	defer close(a.done)
+
+	ticker := time.NewTicker(a.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, a.config.BatchSize)
+	buff := &bytes.Buffer{}
+
+	for {
+		select {
+		case <-a.notify:
+		case <-ticker.C:
+		case ch := <-a.flush:
+			a.drain(batch, buff)
+			close(ch)
+			continue
+		}
+
+		a.drain(batch, buff)
+
+		a.lock.Lock()
+		closed := a.closed && a.n == 0
+		a.lock.Unlock()
+
+		if closed {
+			return
+		}
+	}
+}
+
+// drain 分批写入缓冲区中的所有日志
+func (a *AsyncWriter) drain(batch [][]byte, buff *bytes.Buffer) {
+	for {
+		batch = batch[:0]
+
+		a.lock.Lock()
+		for a.n > 0 && len(batch) < a.config.BatchSize {
+			batch = append(batch, a.ring[a.head])
+			a.ring[a.head] = nil
+			a.head = (a.head + 1) % len(a.ring)
+			a.n--
+		}
+		a.notFull.Broadcast()
+		a.lock.Unlock()
+
+		if len(batch) == 0 {
+			return
+		}
+
+		buff.Reset()
+		for _, p := range batch {
+			buff.Write(p)
+		}
+
+		if _, err := a.w.Write(buff.Bytes()); err != nil && a.config.OnError != nil {
+			a.config.OnError(err)
+		}
+	}
+}
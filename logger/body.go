@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"regexp"
+	"strings"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Body 记录请求体与响应体，用于排查问题，输出前使用 Redact 遮盖敏感数据
+type Body struct {
+	// Request 是否记录请求体
+	Request bool
+
+	// Response 是否记录响应体
+	Response bool
+
+	// MaxSize 每个 body 最多记录的字节数，超出的部分被截断，默认 4096
+	MaxSize int
+
+	// ContentTypes 需要记录的内容类型，支持前缀，如 "text/"
+	// 默认 application/json, application/x-www-form-urlencoded, application/xml, text/
+	ContentTypes []string
+
+	// Paths 需要记录的路径，支持通配符 "*"，为空时记录所有路径
+	Paths []string
+
+	// paths 将 Paths 解析后存储于此
+	paths []*regexp.Regexp
+}
+
+var defaultBodyContentTypes = []string{
+	"application/json",
+	"application/x-www-form-urlencoded",
+	"application/xml",
+	"text/",
+}
+
+// compile 填充默认值并解析路径
+func (b *Body) compile() {
+	if b.MaxSize <= 0 {
+		b.MaxSize = 4096
+	}
+
+	if len(b.ContentTypes) == 0 {
+		b.ContentTypes = defaultBodyContentTypes
+	}
+
+	b.paths = compileGlobs(b.Paths)
+}
+
+// matchPath 路径是否需要记录
+func (b *Body) matchPath(ctx zeroapi.Context) bool {
+	return len(b.paths) == 0 || matchGlobs(b.paths, ctx.Path())
+}
+
+// matchContentType 内容类型是否需要记录
+func (b *Body) matchContentType(contentType string) bool {
+	if contentType == "" {
+		return false
+	}
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	for _, t := range b.ContentTypes {
+		if strings.HasPrefix(contentType, t) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// captureRequest 读取请求体的前 MaxSize 个字节，并还原请求体供后续处理使用
+func (b *Body) captureRequest(req *http.Request) (string, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || !b.matchContentType(req.Header.Get("Content-Type")) {
+		return "", false, nil
+	}
+
+	prefix, err := io.ReadAll(io.LimitReader(req.Body, int64(b.MaxSize)+1))
+	if err != nil {
+		return "", false, err
+	}
+
+	req.Body = &replayReader{
+		Reader: io.MultiReader(bytes.NewReader(prefix), req.Body),
+		Closer: req.Body,
+	}
+
+	if len(prefix) > b.MaxSize {
+		return string(prefix[:b.MaxSize]), true, nil
+	}
+
+	return string(prefix), false, nil
+}
+
+// replayReader 先读取已经读出的部分，再读取剩余的请求体
+type replayReader struct {
+	io.Reader
+	io.Closer
+}
+
+// captureWriter 记录响应体的前 max 个字节
+type captureWriter struct {
+	http.ResponseWriter
+	max       int
+	buff      bytes.Buffer
+	truncated bool
+}
+
+func (w *captureWriter) Write(p []byte) (int, error) {
+	if remain := w.max - w.buff.Len(); remain > 0 {
+		if len(p) > remain {
+			w.buff.Write(p[:remain])
+			w.truncated = true
+		} else {
+			w.buff.Write(p)
+		}
+	} else if len(p) > 0 {
+		w.truncated = true
+	}
+
+	return w.ResponseWriter.Write(p)
+}
+
+// Flush 实现 http.Flusher
+func (w *captureWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker，如 websocket 升级
+func (w *captureWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap 供 http.ResponseController 使用
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Body 遮盖 body 中的敏感数据
+//
+// 表单按查询参数处理，JSON 遮盖名称需要遮盖的字段值，其它内容只使用 Patterns
+func (r *Redactor) Body(contentType, body string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+
+	switch {
+	case contentType == "application/x-www-form-urlencoded":
+		return r.Query(body)
+	case contentType == "application/json" || strings.HasSuffix(contentType, "+json"):
+		return r.Text(r.json(body))
+	default:
+		return r.Text(body)
+	}
+}
+
+// jsonField 匹配 JSON 中的 "name": value，被截断的 JSON 也可以匹配
+var jsonField = regexp.MustCompile(`"((?:[^"\\]|\\.)*)"(\s*:\s*)("(?:[^"\\]|\\.)*"|[^\s,{}\[\]"]+)`)
+
+// json 遮盖 JSON 中名称需要遮盖的字段值
+func (r *Redactor) json(body string) string {
+	return jsonField.ReplaceAllStringFunc(body, func(field string) string {
+		m := jsonField.FindStringSubmatch(field)
+		if !r.Sensitive(m[1]) {
+			return field
+		}
+
+		value := m[3]
+		if len(value) >= 2 && value[0] == '"' {
+			return `"` + m[1] + `"` + m[2] + `"` + r.Mask(value[1:len(value)-1]) + `"`
+		}
+
+		return `"` + m[1] + `"` + m[2] + `"` + r.Mask(value) + `"`
+	})
+}
@@ -0,0 +1,308 @@
+package logger
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Encoder 将一条请求日志编码后写入 buff
+type Encoder interface {
+	Encode(buff *bytes.Buffer, entry *Entry)
+}
+
+// EncoderFunc 函数形式的 Encoder
+type EncoderFunc func(buff *bytes.Buffer, entry *Entry)
+
+// Encode 实现 Encoder
+func (f EncoderFunc) Encode(buff *bytes.Buffer, entry *Entry) {
+	f(buff, entry)
+}
+
+// clfTimeLayout Common Log Format 中的时间格式
+const clfTimeLayout = "02/Jan/2006:15:04:05 -0700"
+
+// TextEncoder 默认格式，如 "ip: 127.0.0.1, method: GET, path: /, code: 200, cost: 1ms, "
+func TextEncoder() Encoder {
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		for i := range entry.Fields {
+			f := &entry.Fields[i]
+
+			// Extend 直接拼接
+			if f.Name == FieldExtend {
+				buff.WriteString(f.String)
+				continue
+			}
+
+			buff.WriteString(f.Key)
+			buff.Write(kvSep)
+			writeText(buff, f)
+			buff.Write(sep)
+		}
+	})
+}
+
+// JSONEncoder 每条日志为一个 JSON 对象，时长以秒为单位
+func JSONEncoder() Encoder {
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		buff.WriteByte('{')
+		for i := range entry.Fields {
+			f := &entry.Fields[i]
+			if i > 0 {
+				buff.WriteByte(',')
+			}
+
+			writeJSONString(buff, f.Key)
+			buff.WriteByte(':')
+
+			switch f.Type {
+			case StringType:
+				writeJSONString(buff, f.String)
+			case IntType:
+				buff.Write(strconv.AppendInt(buff.AvailableBuffer(), f.Int, 10))
+			case DurationType:
+				buff.Write(strconv.AppendFloat(buff.AvailableBuffer(), f.Duration.Seconds(), 'f', -1, 64))
+			case TimeType:
+				buff.WriteByte('"')
+				buff.Write(f.Time.AppendFormat(buff.AvailableBuffer(), time.RFC3339Nano))
+				buff.WriteByte('"')
+			}
+		}
+		buff.WriteByte('}')
+	})
+}
+
+// LogfmtEncoder logfmt 格式，如 `ip=127.0.0.1 method=GET path=/ code=200 cost=1ms`
+func LogfmtEncoder() Encoder {
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		for i := range entry.Fields {
+			f := &entry.Fields[i]
+			if i > 0 {
+				buff.WriteByte(' ')
+			}
+
+			buff.WriteString(f.Key)
+			buff.WriteByte('=')
+
+			if f.Type == StringType {
+				if needsQuote(f.String) {
+					buff.Write(strconv.AppendQuote(buff.AvailableBuffer(), f.String))
+				} else {
+					buff.WriteString(f.String)
+				}
+				continue
+			}
+
+			writeText(buff, f)
+		}
+	})
+}
+
+// CommonEncoder Apache Common Log Format
+//
+// host ident authuser [date] "request" status bytes
+func CommonEncoder() Encoder {
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		writeCommon(buff, entry)
+	})
+}
+
+// CombinedEncoder Apache Combined Log Format
+//
+// host ident authuser [date] "request" status bytes "referer" "user-agent"
+func CombinedEncoder() Encoder {
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		writeCommon(buff, entry)
+		buff.WriteString(` "`)
+		writeField(buff, entry, FieldReferer)
+		buff.WriteString(`" "`)
+		writeField(buff, entry, FieldUserAgent)
+		buff.WriteByte('"')
+	})
+}
+
+// TemplateEncoder 自定义模板，使用 {name} 引用字段，字段不存在时输出 "-"
+//
+// 如 "{ip} {method} {path} {code} {cost}"
+func TemplateEncoder(template string) Encoder {
+	// 预先拆分为文本与字段
+	type segment struct {
+		text  string
+		field string
+	}
+
+	segments := make([]segment, 0)
+	for len(template) > 0 {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			segments = append(segments, segment{text: template})
+			break
+		}
+
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			segments = append(segments, segment{text: template})
+			break
+		}
+
+		if start > 0 {
+			segments = append(segments, segment{text: template[:start]})
+		}
+		segments = append(segments, segment{field: template[start+1 : start+end]})
+		template = template[start+end+1:]
+	}
+
+	return EncoderFunc(func(buff *bytes.Buffer, entry *Entry) {
+		for _, s := range segments {
+			if s.field == "" {
+				buff.WriteString(s.text)
+				continue
+			}
+
+			writeField(buff, entry, s.field)
+		}
+	})
+}
+
+// writeCommon host ident authuser [date] "request" status bytes
+func writeCommon(buff *bytes.Buffer, entry *Entry) {
+	writeField(buff, entry, FieldIP)
+	buff.WriteString(" - ")
+	writeField(buff, entry, FieldPrincipal)
+
+	buff.WriteString(" [")
+	buff.Write(entry.Time.AppendFormat(buff.AvailableBuffer(), clfTimeLayout))
+	buff.WriteString(`] "`)
+
+	writeField(buff, entry, FieldMethod)
+	buff.WriteByte(' ')
+	writeField(buff, entry, FieldPath)
+	if f, ok := entry.Get(FieldQuery); ok && len(f.String) > 0 {
+		buff.WriteByte('?')
+		writeEscaped(buff, f.String)
+	}
+	if f, ok := entry.Get(FieldProto); ok && len(f.String) > 0 {
+		buff.WriteByte(' ')
+		writeEscaped(buff, f.String)
+	}
+
+	buff.WriteString(`" `)
+	writeField(buff, entry, FieldCode)
+	buff.WriteByte(' ')
+	writeField(buff, entry, FieldBytesOut)
+}
+
+// writeField 输出字段的值，字段不存在或者为空字符串时输出 "-"
+func writeField(buff *bytes.Buffer, entry *Entry, name string) {
+	f, ok := entry.Get(name)
+	if !ok || (f.Type == StringType && len(f.String) == 0) {
+		buff.WriteByte('-')
+		return
+	}
+
+	writeText(buff, f)
+}
+
+// writeText 以文本形式输出字段的值，字符串按 Apache 的方式转义
+func writeText(buff *bytes.Buffer, f *Field) {
+	switch f.Type {
+	case StringType:
+		writeEscaped(buff, f.String)
+	case IntType:
+		buff.Write(strconv.AppendInt(buff.AvailableBuffer(), f.Int, 10))
+	case DurationType:
+		buff.WriteString(f.Duration.String())
+	case TimeType:
+		buff.Write(f.Time.AppendFormat(buff.AvailableBuffer(), time.RFC3339Nano))
+	}
+}
+
+// needsQuote logfmt 中包含空白、"="、引号或者控制字符的值需要使用引号
+func needsQuote(s string) bool {
+	if len(s) == 0 {
+		return true
+	}
+
+	for _, r := range s {
+		if r <= ' ' || r == '=' || r == '"' || r == utf8.RuneError {
+			return true
+		}
+	}
+
+	return false
+}
+
+const hexDigits = "0123456789abcdef"
+
+// writeEscaped 与 Apache 的访问日志相同，转义引号、反斜杠与控制字符，避免伪造日志行或者字段
+//
+// 如 `"` 转为 `\"`，换行转为 `\n`，其它控制字符转为 `\xhh`
+func writeEscaped(buff *bytes.Buffer, s string) {
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != 0x7f && c != '"' && c != '\\' {
+			continue
+		}
+
+		buff.WriteString(s[start:i])
+		switch c {
+		case '"', '\\':
+			buff.WriteByte('\\')
+			buff.WriteByte(c)
+		case '\b':
+			buff.WriteString(`\b`)
+		case '\n':
+			buff.WriteString(`\n`)
+		case '\r':
+			buff.WriteString(`\r`)
+		case '\t':
+			buff.WriteString(`\t`)
+		case '\v':
+			buff.WriteString(`\v`)
+		default:
+			buff.WriteString(`\x`)
+			buff.WriteByte(hexDigits[c>>4])
+			buff.WriteByte(hexDigits[c&0xf])
+		}
+		start = i + 1
+	}
+
+	buff.WriteString(s[start:])
+}
+
+// writeJSONString 输出 JSON 字符串，转义引号、反斜杠与控制字符
+func writeJSONString(buff *bytes.Buffer, s string) {
+	buff.WriteByte('"')
+
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= 0x20 && c != '"' && c != '\\' {
+			continue
+		}
+
+		buff.WriteString(s[start:i])
+		switch c {
+		case '"', '\\':
+			buff.WriteByte('\\')
+			buff.WriteByte(c)
+		case '\n':
+			buff.WriteString(`\n`)
+		case '\r':
+			buff.WriteString(`\r`)
+		case '\t':
+			buff.WriteString(`\t`)
+		default:
+			buff.WriteString(`\u00`)
+			buff.WriteByte(hexDigits[c>>4])
+			buff.WriteByte(hexDigits[c&0xf])
+		}
+		start = i + 1
+	}
+
+	buff.WriteString(s[start:])
+	buff.WriteByte('"')
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"sync"
+	"time"
+)
+
+// 字段名称
+const (
+	FieldTime   = "time"
+	FieldIP     = "ip"
+	FieldMethod = "method"
+	FieldPath   = "path"
+	FieldCode   = "code"
+	FieldCost   = "cost"
+	FieldExtend = "extend"
+
+	FieldQuery     = "query"
+	FieldProto     = "proto"
+	FieldHost      = "host"
+	FieldBytesIn   = "bytes_in"
+	FieldBytesOut  = "bytes_out"
+	FieldUserAgent = "user_agent"
+	FieldReferer   = "referer"
+	FieldRoute     = "route"
+	FieldRequestID = "request_id"
+	FieldPrincipal = "principal"
+	FieldLevel     = "level"
+	FieldPanic     = "panic"
+
+	FieldRequestBody  = "req_body"
+	FieldResponseBody = "resp_body"
+
+	// FieldRequestHeader 请求头字段的前缀，如 "req.X-Tenant-Id"
+	FieldRequestHeader = "req."
+	// FieldResponseHeader 响应头字段的前缀，如 "resp.Content-Type"
+	FieldResponseHeader = "resp."
+)
+
+// FieldType 字段类型
+type FieldType uint8
+
+const (
+	// StringType 字符串
+	StringType FieldType = iota
+	// IntType 整数
+	IntType
+	// DurationType 时长
+	DurationType
+	// TimeType 时间
+	TimeType
+)
+
+// Field 日志字段
+type Field struct {
+	// Name 字段名称，如 FieldIP
+	Name string
+	// Key 输出时使用的名称，默认与 Name 相同
+	Key string
+
+	Type     FieldType
+	String   string
+	Int      int64
+	Duration time.Duration
+	Time     time.Time
+}
+
+// Entry 一条请求日志
+type Entry struct {
+	// Time 请求开始的时间
+	Time time.Time
+
+	// Fields 按顺序输出的字段
+	Fields []Field
+}
+
+// Get 根据名称获取字段
+func (e *Entry) Get(name string) (*Field, bool) {
+	for i := range e.Fields {
+		if e.Fields[i].Name == name {
+			return &e.Fields[i], true
+		}
+	}
+
+	return nil, false
+}
+
+// AddString 添加字符串字段
+func (e *Entry) AddString(name, value string) {
+	e.Fields = append(e.Fields, Field{Name: name, Key: name, Type: StringType, String: value})
+}
+
+// AddInt 添加整数字段
+func (e *Entry) AddInt(name string, value int64) {
+	e.Fields = append(e.Fields, Field{Name: name, Key: name, Type: IntType, Int: value})
+}
+
+// AddDuration 添加时长字段
+func (e *Entry) AddDuration(name string, value time.Duration) {
+	e.Fields = append(e.Fields, Field{Name: name, Key: name, Type: DurationType, Duration: value})
+}
+
+// AddTime 添加时间字段
+func (e *Entry) AddTime(name string, value time.Time) {
+	e.Fields = append(e.Fields, Field{Name: name, Key: name, Type: TimeType, Time: value})
+}
+
+var entryPool = &sync.Pool{
+	New: func() interface{} {
+		return &Entry{Fields: make([]Field, 0, 16)}
+	},
+}
+
+// acquireEntry 从池中获取 entry
+func acquireEntry() *Entry {
+	return entryPool.Get().(*Entry)
+}
+
+// releaseEntry 将 entry 放入池中
+func releaseEntry(e *Entry) {
+	e.Fields = e.Fields[:0]
+	entryPool.Put(e)
+}
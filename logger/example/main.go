@@ -27,6 +27,101 @@ func main() {
 
 	a.Use(zamlogger.New())
 
+	// JSON 格式，输出到标准输出
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	Time:    true,
+	// 	IP:      true,
+	// 	Code:    true,
+	// 	Cost:    true,
+	// 	Encoder: zamlogger.JSONEncoder(),
+	// 	Output:  os.Stdout,
+	// }))
+	//
+	// Combined Log Format
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:        true,
+	// 	Code:      true,
+	// 	Query:     true,
+	// 	Proto:     true,
+	// 	BytesOut:  true,
+	// 	UserAgent: true,
+	// 	Referer:   true,
+	// 	Encoder:   zamlogger.CombinedEncoder(),
+	// }))
+	//
+	// 更多字段，并修改字段名称
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:              true,
+	// 	Code:            true,
+	// 	Cost:            true,
+	// 	RequestID:       true,
+	// 	RequestHeaders:  []string{"X-Tenant-Id"},
+	// 	ResponseHeaders: []string{"Content-Type"},
+	// 	Rename:          map[string]string{"cost": "duration", "req.X-Tenant-Id": "tenant"},
+	// 	Encoder:         zamlogger.JSONEncoder(),
+	// }))
+	//
+	// 遮盖敏感数据，相同的值输出相同的 HMAC 前缀，多个进程之间关联时需设置相同的 HashKey
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:             true,
+	// 	Code:           true,
+	// 	Query:          true,
+	// 	RequestHeaders: []string{"Authorization", "X-Api-Key"},
+	// 	Redact: &zamlogger.Redactor{
+	// 		Keys:     []string{"X-Partner-Secret", "card_no"},
+	// 		Patterns: []string{`\d{16}`},
+	// 		Mode:     zamlogger.MaskHash,
+	// 		HashKey:  []byte(os.Getenv("LOG_HASH_KEY")),
+	// 	},
+	// }))
+	//
+	// 记录合作方接口的请求体与响应体，最多 2KB
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:   true,
+	// 	Code: true,
+	// 	Body: &zamlogger.Body{
+	// 		Request:  true,
+	// 		Response: true,
+	// 		MaxSize:  2048,
+	// 		Paths:    []string{"/partner/*"},
+	// 	},
+	// 	Encoder: zamlogger.JSONEncoder(),
+	// }))
+	//
+	// 跳过健康检查与静态资源，采样 10%，错误与慢请求总是保留
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:         true,
+	// 	Code:       true,
+	// 	Cost:       true,
+	// 	Skip:       &zamlogger.Skip{Paths: []string{"/health", "/static/*"}, Methods: []string{"OPTIONS"}},
+	// 	SampleRate: 0.1,
+	// 	Slow:       500 * time.Millisecond,
+	// 	Level:      true,
+	// }))
+	//
+	// 异步写入文件，缓冲区已满时丢弃最旧的日志
+	// w := zamlogger.NewAsyncWriter(file, &zamlogger.AsyncConfig{
+	// 	Size:   16384,
+	// 	Policy: zamlogger.DropOldest,
+	// })
+	// defer w.Close()
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	Time:    true,
+	// 	IP:      true,
+	// 	Code:    true,
+	// 	Cost:    true,
+	// 	Encoder: zamlogger.JSONEncoder(),
+	// 	Output:  w,
+	// }))
+	//
+	// 自定义模板
+	// a.Use(zamlogger.New(&zamlogger.Config{
+	// 	IP:      true,
+	// 	Code:    true,
+	// 	Cost:    true,
+	// 	Encoder: zamlogger.TemplateEncoder("{ip} {method} {path} {code} {cost}"),
+	// }))
+
 	// 监听信号，比如优雅关闭
 	a.Server().HTTPServer().ListenSignal()
 
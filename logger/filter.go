@@ -0,0 +1,169 @@
+package logger
+
+import (
+	"math/rand"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Level 日志级别
+type Level int
+
+const (
+	// LevelInfo 正常请求
+	LevelInfo Level = iota
+	// LevelWarn 4xx 或者慢请求
+	LevelWarn
+	// LevelError 5xx
+	LevelError
+)
+
+// String 级别名称，用于日志字段 level
+func (l Level) String() string {
+	switch l {
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	default:
+		return "info"
+	}
+}
+
+// Skip 跳过规则，满足任意一项的请求不打印日志
+type Skip struct {
+	// Paths 路径，支持通配符 "*"，如 "/static/*"
+	Paths []string
+
+	// Methods 请求方法，如 OPTIONS
+	Methods []string
+
+	// Codes 状态码范围，闭区间，如 {{200, 299}, {304, 304}}
+	Codes [][2]int
+
+	// paths 将 Paths 解析后存储于此
+	paths []*regexp.Regexp
+}
+
+// compile 解析路径中的通配符
+func (s *Skip) compile() {
+	s.paths = compileGlobs(s.Paths)
+}
+
+// compileGlobs 将包含通配符 "*" 的路径解析为正则表达式
+func compileGlobs(paths []string) []*regexp.Regexp {
+	globs := make([]*regexp.Regexp, 0, len(paths))
+	for _, p := range paths {
+		pattern := regexp.QuoteMeta(p)
+		pattern = strings.Replace(pattern, "\\*", ".*", -1)
+		globs = append(globs, regexp.MustCompile("^"+pattern+"$"))
+	}
+
+	return globs
+}
+
+// matchGlobs 路径是否与任意一个正则表达式匹配
+func matchGlobs(globs []*regexp.Regexp, path string) bool {
+	for _, g := range globs {
+		if g.MatchString(path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// skipRequest 在请求开始时判断，只依赖方法与路径
+func (s *Skip) skipRequest(ctx zeroapi.Context) bool {
+	for _, method := range s.Methods {
+		if strings.EqualFold(method, ctx.Method()) {
+			return true
+		}
+	}
+
+	return matchGlobs(s.paths, ctx.Path())
+}
+
+// skipCode 在请求结束时判断状态码
+func (s *Skip) skipCode(code int) bool {
+	for _, r := range s.Codes {
+		if code >= r[0] && code <= r[1] {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sampler 采样，错误请求(5xx)与慢请求总是保留
+type sampler struct {
+	// rate 保留的比例，(0, 1)
+	rate float64
+
+	// perSecond 每秒最多保留的个数
+	perSecond int
+
+	lock   sync.Mutex
+	second int64
+	count  int
+	rand   *rand.Rand
+}
+
+func newSampler(rate float64, perSecond int) *sampler {
+	if (rate <= 0 || rate >= 1) && perSecond <= 0 {
+		return nil
+	}
+
+	return &sampler{
+		rate:      rate,
+		perSecond: perSecond,
+		rand:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// keep 是否保留该条日志
+func (s *sampler) keep(now time.Time) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.rate > 0 && s.rate < 1 && s.rand.Float64() >= s.rate {
+		return false
+	}
+
+	if s.perSecond > 0 {
+		second := now.Unix()
+		if second != s.second {
+			s.second = second
+			s.count = 0
+		}
+
+		if s.count >= s.perSecond {
+			return false
+		}
+		s.count++
+	}
+
+	return true
+}
+
+// level 根据状态码与耗时选择日志级别
+func (c *Config) level(code int, cost time.Duration) Level {
+	if code >= http.StatusInternalServerError {
+		return LevelError
+	}
+
+	if code >= http.StatusBadRequest {
+		return LevelWarn
+	}
+
+	if c.Slow > 0 && cost >= c.Slow {
+		return LevelWarn
+	}
+
+	return LevelInfo
+}
@@ -2,17 +2,27 @@
 package logger
 
 import (
+	"bufio"
 	"bytes"
-	"strconv"
+	"io"
+	"net"
+	"net/http"
 	"sync"
 	"time"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
-	zerobytes "github.com/zerogo-hub/zero-helper/bytes"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/recovery"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // Config 配置
 type Config struct {
+	// Time 是否打印请求开始的时间，默认 false，由 app 的日志打印时间
+	// 使用 Output 输出时建议开启
+	Time bool
+
 	// IP 是否打印 IP，默认 true
 	IP bool
 
@@ -22,8 +32,81 @@ type Config struct {
 	// Cost 是否打印花费的时间，默认 true
 	Cost bool
 
+	// Query 是否打印查询参数
+	Query bool
+
+	// Proto 是否打印协议，如 HTTP/1.1
+	Proto bool
+
+	// Host 是否打印请求的 Host
+	Host bool
+
+	// BytesIn 是否打印请求体的字节数，Content-Length 未知时为实际读取的字节数
+	BytesIn bool
+
+	// BytesOut 是否打印响应体的字节数，通过包装 ctx.Response().Writer() 统计
+	BytesOut bool
+
+	// UserAgent 是否打印 User-Agent
+	UserAgent bool
+
+	// Referer 是否打印 Referer
+	Referer bool
+
+	// RequestID 是否打印请求 id，需要使用 requestid 中间件，不使用请求头中未经校验的 X-Request-ID
+	RequestID bool
+
+	// Route 获取匹配的路由，如 "/user/:id"，为 nil 时不打印
+	Route func(ctx zeroapi.Context) string
+
+	// Principal 获取已认证的用户，如 jwt 中的用户标识，为 nil 时不打印
+	Principal func(ctx zeroapi.Context) string
+
+	// RequestHeaders 需要打印的请求头，字段名称为 "req." + 规范化的请求头
+	RequestHeaders []string
+
+	// ResponseHeaders 需要打印的响应头，字段名称为 "resp." + 规范化的响应头
+	ResponseHeaders []string
+
+	// Rename 修改字段输出时的名称，如 {"cost": "duration"}
+	Rename map[string]string
+
 	// Extend 日志扩展，拼接该函数的返回结果
 	Extend func(ctx zeroapi.Context) string
+
+	// Redact 遮盖查询参数、Referer、请求头、响应头与 Extend 中的敏感数据
+	// 默认遮盖 DefaultRedactKeys 中的名称
+	Redact *Redactor
+
+	// Body 记录请求体与响应体，默认不记录
+	Body *Body
+
+	// Skip 跳过规则，如健康检查与静态资源
+	Skip *Skip
+
+	// SampleRate 采样比例，(0, 1) 之间有效，如 0.1 表示保留 10% 的日志
+	// 错误请求(5xx)与慢请求总是保留
+	SampleRate float64
+
+	// SamplePerSecond 每秒最多保留的日志个数，0 表示不限制
+	// 错误请求(5xx)与慢请求总是保留，且不计入个数
+	SamplePerSecond int
+
+	// Slow 慢请求的阈值，耗时超过该值的请求总是保留，开启 Level 时使用 Warn 级别打印
+	Slow time.Duration
+
+	// Level 是否按状态码与耗时选择日志级别，默认 false，均使用 Info 级别
+	// 5xx 使用 Error，4xx 与慢请求使用 Warn，并增加字段 level
+	// 被 recovery 捕获的异常总是使用 Error 级别，并增加字段 panic
+	Level bool
+
+	// Encoder 日志格式，默认为 TextEncoder
+	// 可选 JSONEncoder, LogfmtEncoder, CommonEncoder, CombinedEncoder, TemplateEncoder
+	Encoder Encoder
+
+	// Output 日志输出，每条日志一行，默认使用 app 的日志
+	// 使用 NewAsyncWriter 异步、批量写入
+	Output io.Writer
 }
 
 var defaultConfig = &Config{
@@ -33,12 +116,8 @@ var defaultConfig = &Config{
 }
 
 var (
-	ipFlag     = []byte("ip: ")
-	sep        = []byte(", ")
-	methodFlag = []byte("method: ")
-	pathFlag   = []byte("path: ")
-	codeFlag   = []byte("code: ")
-	costFlag   = []byte("cost: ")
+	sep   = []byte(", ")
+	kvSep = []byte(": ")
 )
 
 // New 记录每一条请求的信息
@@ -50,49 +129,334 @@ func New(config ...*Config) zeroapi.Handler {
 		c = defaultConfig
 	}
 
+	encoder := c.Encoder
+	if encoder == nil {
+		encoder = TextEncoder()
+	}
+
+	var output io.Writer
+	if a, ok := c.Output.(*AsyncWriter); ok {
+		output = a
+	} else if c.Output != nil {
+		output = &lockedWriter{w: c.Output}
+	}
+
+	if c.Skip != nil {
+		c.Skip.compile()
+	}
+
+	if c.Redact != nil {
+		c.Redact.compile()
+	}
+
+	if c.Body != nil {
+		c.Body.compile()
+	}
+
+	sample := newSampler(c.SampleRate, c.SamplePerSecond)
+
 	return func(ctx zeroapi.Context) {
+		if c.Skip != nil && c.Skip.skipRequest(ctx) {
+			return
+		}
+
 		start := time.Now()
+		st := &state{}
+
+		if c.Body != nil && c.Body.matchPath(ctx) {
+			if c.Body.Request {
+				body, truncated, err := c.Body.captureRequest(ctx.Request())
+				if err != nil {
+					ctx.App().Logger().Errorf("capture request body failed, err: %s, method: %s, path: %s, request_id: %s", err.Error(), ctx.Method(), ctx.Path(), requestid.Get(ctx))
+				}
+				st.requestBody, st.requestTruncated = body, truncated
+			}
+
+			if c.Body.Response {
+				st.capture = &captureWriter{ResponseWriter: ctx.Response().Writer(), max: c.Body.MaxSize}
+				ctx.Response().SetWriter(st.capture)
+			}
+		}
+
+		if c.BytesIn && ctx.Request().ContentLength < 0 && ctx.Request().Body != nil && ctx.Request().Body != http.NoBody {
+			st.body = &countingReader{ReadCloser: ctx.Request().Body}
+			ctx.Request().Body = st.body
+		}
+
+		if c.BytesOut {
+			st.writer = &countingWriter{ResponseWriter: ctx.Response().Writer()}
+			ctx.Response().SetWriter(st.writer)
+		}
 
 		ctx.AppendEnd(func() error {
-			buff := buffer()
-			defer releaseBuffer(buff)
+			code := ctx.HTTPCode()
+			cost := time.Since(start)
+
+			if c.Skip != nil && c.Skip.skipCode(code) {
+				return nil
+			}
+
+			p := recovery.From(ctx)
 
-			if c.IP {
-				buff.Write(ipFlag)
-				buff.Write(zerobytes.StringToBytes(ctx.IP()))
-				buff.Write(sep)
+			slow := c.Slow > 0 && cost >= c.Slow
+			if sample != nil && p == nil && code < http.StatusInternalServerError && !slow && !sample.keep(start) {
+				return nil
 			}
 
-			buff.Write(methodFlag)
-			buff.Write(zerobytes.StringToBytes(ctx.Method()))
-			buff.Write(sep)
-			buff.Write(pathFlag)
-			buff.Write(zerobytes.StringToBytes(ctx.Path()))
-			buff.Write(sep)
-
-			if c.Code {
-				buff.Write(codeFlag)
-				buff.Write(zerobytes.StringToBytes(strconv.Itoa(ctx.HTTPCode())))
-				buff.Write(sep)
+			level := LevelInfo
+			if p != nil {
+				level = LevelError
+			} else if c.Level {
+				level = c.level(code, cost)
 			}
 
-			if c.Cost {
-				cost := time.Since(start)
-				buff.Write(costFlag)
-				buff.Write(zerobytes.StringToBytes(cost.String()))
-				buff.Write(sep)
+			entry := acquireEntry()
+			defer releaseEntry(entry)
+
+			c.fill(ctx, entry, start, cost, st)
+
+			if p != nil {
+				entry.AddString(FieldPanic, c.redactor().Text(p.Error()))
 			}
 
-			if c.Extend != nil {
-				buff.Write(zerobytes.StringToBytes(c.Extend(ctx)))
+			if c.Level {
+				entry.AddString(FieldLevel, level.String())
 			}
 
-			ctx.App().Logger().Info(buff.String())
+			if len(c.Rename) > 0 {
+				c.rename(entry)
+			}
+
+			buff := buffer()
+			defer releaseBuffer(buff)
+
+			encoder.Encode(buff, entry)
+
+			if output != nil {
+				buff.WriteByte('\n')
+				if _, err := output.Write(buff.Bytes()); err != nil {
+					ctx.App().Logger().Errorf("write access log failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+				}
+				return nil
+			}
+
+			switch level {
+			case LevelError:
+				ctx.App().Logger().Error(buff.String())
+			case LevelWarn:
+				ctx.App().Logger().Warn(buff.String())
+			default:
+				ctx.App().Logger().Info(buff.String())
+			}
 			return nil
 		})
 	}
 }
 
+// fill 填充日志字段
+func (c *Config) fill(ctx zeroapi.Context, entry *Entry, start time.Time, cost time.Duration, st *state) {
+	entry.Time = start
+
+	if c.Time {
+		entry.AddTime(FieldTime, start)
+	}
+
+	if c.IP {
+		entry.AddString(FieldIP, realip.IP(ctx))
+	}
+
+	redact := c.redactor()
+
+	entry.AddString(FieldMethod, ctx.Method())
+	entry.AddString(FieldPath, redact.Text(ctx.Path()))
+
+	req := ctx.Request()
+
+	if c.Query {
+		entry.AddString(FieldQuery, redact.Query(req.URL.RawQuery))
+	}
+
+	if c.Proto {
+		entry.AddString(FieldProto, req.Proto)
+	}
+
+	if c.Host {
+		entry.AddString(FieldHost, req.Host)
+	}
+
+	if c.Code {
+		entry.AddInt(FieldCode, int64(ctx.HTTPCode()))
+	}
+
+	if c.Cost {
+		entry.AddDuration(FieldCost, cost)
+	}
+
+	if c.BytesIn {
+		n := req.ContentLength
+		if st.body != nil {
+			n = st.body.n
+		}
+		if n < 0 {
+			n = 0
+		}
+		entry.AddInt(FieldBytesIn, n)
+	}
+
+	if st.writer != nil {
+		entry.AddInt(FieldBytesOut, st.writer.n)
+	}
+
+	if c.UserAgent {
+		entry.AddString(FieldUserAgent, req.UserAgent())
+	}
+
+	if c.Referer {
+		entry.AddString(FieldReferer, redact.URL(req.Referer()))
+	}
+
+	if c.Route != nil {
+		entry.AddString(FieldRoute, c.Route(ctx))
+	}
+
+	if c.RequestID {
+		entry.AddString(FieldRequestID, requestid.Get(ctx))
+	}
+
+	if c.Principal != nil {
+		entry.AddString(FieldPrincipal, c.Principal(ctx))
+	}
+
+	for _, name := range c.RequestHeaders {
+		entry.AddString(FieldRequestHeader+http.CanonicalHeaderKey(name), redact.Value(name, req.Header.Get(name)))
+	}
+
+	if len(c.ResponseHeaders) > 0 {
+		header := ctx.Response().Writer().Header()
+		for _, name := range c.ResponseHeaders {
+			entry.AddString(FieldResponseHeader+http.CanonicalHeaderKey(name), redact.Value(name, header.Get(name)))
+		}
+	}
+
+	if st.requestBody != "" {
+		entry.AddString(FieldRequestBody, truncate(redact.Body(req.Header.Get("Content-Type"), st.requestBody), st.requestTruncated))
+	}
+
+	if st.capture != nil && st.capture.buff.Len() > 0 {
+		contentType := ctx.Response().Writer().Header().Get("Content-Type")
+		if c.Body.matchContentType(contentType) {
+			entry.AddString(FieldResponseBody, truncate(redact.Body(contentType, st.capture.buff.String()), st.capture.truncated))
+		}
+	}
+
+	if c.Extend != nil {
+		entry.AddString(FieldExtend, redact.Text(c.Extend(ctx)))
+	}
+}
+
+// state 一次请求中记录的数据
+type state struct {
+	// body 统计请求体的字节数
+	body *countingReader
+
+	// writer 统计响应体的字节数
+	writer *countingWriter
+
+	// capture 记录响应体
+	capture *captureWriter
+
+	// requestBody 记录的请求体
+	requestBody string
+
+	// requestTruncated 请求体是否被截断
+	requestTruncated bool
+}
+
+// truncate 被截断的 body 增加标记
+func truncate(body string, truncated bool) string {
+	if truncated {
+		return body + "...(truncated)"
+	}
+
+	return body
+}
+
+// redactor 未配置 Redact 时使用内置规则
+func (c *Config) redactor() *Redactor {
+	if c.Redact != nil {
+		return c.Redact
+	}
+
+	return defaultRedactor
+}
+
+// rename 修改字段输出时的名称
+func (c *Config) rename(entry *Entry) {
+	for i := range entry.Fields {
+		if key, ok := c.Rename[entry.Fields[i].Name]; ok {
+			entry.Fields[i].Key = key
+		}
+	}
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	io.ReadCloser
+	n int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+// countingWriter 统计写入的字节数
+type countingWriter struct {
+	http.ResponseWriter
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// Flush 实现 http.Flusher
+func (w *countingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack 实现 http.Hijacker，如 websocket 升级
+func (w *countingWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap 供 http.ResponseController 使用
+func (w *countingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// lockedWriter 多个请求并发写入时加锁
+type lockedWriter struct {
+	lock sync.Mutex
+	w    io.Writer
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
+	return w.w.Write(p)
+}
+
 var bufferPool *sync.Pool
 
 // buffer 从池中获取 buffer
@@ -107,7 +471,11 @@ func releaseBuffer(buff *bytes.Buffer) {
 	bufferPool.Put(buff)
 }
 
+var defaultRedactor = &Redactor{}
+
 func init() {
+	defaultRedactor.compile()
+
 	bufferPool = &sync.Pool{}
 	bufferPool.New = func() interface{} {
 		return &bytes.Buffer{}
@@ -0,0 +1,191 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// MaskMode 遮盖方式
+type MaskMode int
+
+const (
+	// MaskLength 使用等长的 "*" 代替，保留长度
+	MaskLength MaskMode = iota
+	// MaskHash 使用 HMAC-SHA256 的前缀代替，如 "hmac:1a2b3c4d"，相同的值可以关联
+	// 密钥为 HashKey，未设置时使用进程启动时随机生成的密钥，无法通过字典反查密码等短值
+	MaskHash
+)
+
+// DefaultRedactKeys 内置需要遮盖的名称，用于请求头、响应头与查询参数，不区分大小写
+//
+// 包含 jwt 与 auth 使用的 Authorization 与 token 参数，csrf、sign 与 nonce 的默认参数，以及常见的令牌与密钥
+var DefaultRedactKeys = []string{
+	"Authorization",
+	"Proxy-Authorization",
+	"Cookie",
+	"Set-Cookie",
+	"X-Csrf-Token",
+	"X-Api-Key",
+	"_csrf",
+	"sign",
+	"nonce",
+	"password",
+	"token",
+	"access_token",
+	"refresh_token",
+	"id_token",
+	"api_key",
+	"apikey",
+	"client_secret",
+}
+
+// Redactor 遮盖日志中的敏感数据
+type Redactor struct {
+	// Keys 除 DefaultRedactKeys 以外需要遮盖的名称，如 "X-Partner-Secret", "card_no"
+	Keys []string
+
+	// Patterns 正则表达式，值中匹配的部分被遮盖，如 `Bearer\s+\S+`
+	Patterns []string
+
+	// Mode 遮盖方式，默认 MaskLength
+	Mode MaskMode
+
+	// HashPrefix MaskHash 保留的十六进制字符个数，默认 8
+	HashPrefix int
+
+	// HashKey MaskHash 计算 HMAC 的密钥，应与日志分开保存
+	// 为空时每个进程随机生成，同一进程内相同的值可以关联，多个进程或者重启后无法关联
+	HashKey []byte
+
+	// NoDefault 是否不使用 DefaultRedactKeys
+	NoDefault bool
+
+	// keys 小写的名称
+	keys map[string]struct{}
+
+	// patterns 将 Patterns 解析后存储于此
+	patterns []*regexp.Regexp
+
+	// hashKey MaskHash 使用的密钥
+	hashKey []byte
+}
+
+// processHashKey 未设置 HashKey 时使用的密钥，每个进程随机生成
+var processHashKey = func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic(err)
+	}
+	return key
+}()
+
+// compile 解析名称与正则表达式
+func (r *Redactor) compile() {
+	if r.HashPrefix <= 0 || r.HashPrefix > sha256.Size*2 {
+		r.HashPrefix = 8
+	}
+
+	r.hashKey = processHashKey
+	if len(r.HashKey) > 0 {
+		r.hashKey = append([]byte(nil), r.HashKey...)
+	}
+
+	r.keys = make(map[string]struct{}, len(DefaultRedactKeys)+len(r.Keys))
+	if !r.NoDefault {
+		for _, key := range DefaultRedactKeys {
+			r.keys[strings.ToLower(key)] = struct{}{}
+		}
+	}
+	for _, key := range r.Keys {
+		r.keys[strings.ToLower(key)] = struct{}{}
+	}
+
+	r.patterns = make([]*regexp.Regexp, 0, len(r.Patterns))
+	for _, pattern := range r.Patterns {
+		r.patterns = append(r.patterns, regexp.MustCompile(pattern))
+	}
+}
+
+// Mask 遮盖整个值
+func (r *Redactor) Mask(value string) string {
+	if value == "" {
+		return value
+	}
+
+	if r.Mode == MaskHash {
+		key := r.hashKey
+		if key == nil {
+			key = processHashKey
+		}
+
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(value))
+		return "hmac:" + hex.EncodeToString(h.Sum(nil))[:r.HashPrefix]
+	}
+
+	return strings.Repeat("*", len(value))
+}
+
+// Sensitive 名称是否需要遮盖
+func (r *Redactor) Sensitive(name string) bool {
+	_, ok := r.keys[strings.ToLower(name)]
+	return ok
+}
+
+// Value 遮盖名称为 name 的值，名称不需要遮盖时，只遮盖值中与 Patterns 匹配的部分
+func (r *Redactor) Value(name, value string) string {
+	if r.Sensitive(name) {
+		return r.Mask(value)
+	}
+
+	return r.Text(value)
+}
+
+// Text 遮盖文本中与 Patterns 匹配的部分
+func (r *Redactor) Text(text string) string {
+	for _, p := range r.patterns {
+		text = p.ReplaceAllStringFunc(text, r.Mask)
+	}
+
+	return text
+}
+
+// Query 遮盖查询参数中需要遮盖的值，保留参数的顺序
+func (r *Redactor) Query(rawQuery string) string {
+	if rawQuery == "" {
+		return rawQuery
+	}
+
+	parts := strings.Split(rawQuery, "&")
+	for i, part := range parts {
+		name, value, ok := strings.Cut(part, "=")
+		if !ok {
+			continue
+		}
+
+		if key, err := url.QueryUnescape(name); err == nil {
+			name = key
+		}
+
+		if r.Sensitive(name) {
+			parts[i] = part[:len(part)-len(value)] + r.Mask(value)
+		}
+	}
+
+	return r.Text(strings.Join(parts, "&"))
+}
+
+// URL 遮盖 url 中的查询参数，如 Referer
+func (r *Redactor) URL(rawURL string) string {
+	prefix, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return r.Text(rawURL)
+	}
+
+	return r.Text(prefix) + "?" + r.Query(query)
+}
@@ -4,6 +4,9 @@ import (
 	"net/http"
 
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 对请求中的必要参数进行验证
@@ -18,7 +21,7 @@ func New(opts ...Option) zeroapi.Handler {
 		if params == nil {
 			ctx.Stopped()
 			ctx.SetHTTPCode(http.StatusBadRequest)
-			ctx.App().Logger().Errorf("params is null, method: %s, path: %s, ip: %s", ctx.Method(), ctx.Path(), ctx.IP())
+			ctx.App().Logger().Errorf("params is null, method: %s, path: %s, ip: %s, request_id: %s", ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 			return
 		}
 
@@ -28,14 +31,14 @@ func New(opts ...Option) zeroapi.Handler {
 			if !ok || len(param) == 0 {
 				ctx.Stopped()
 				ctx.SetHTTPCode(http.StatusBadRequest)
-				ctx.App().Logger().Errorf("miss param: %s, method: %s, path: %s, ip: %s", field.Name, ctx.Method(), ctx.Path(), ctx.IP())
+				ctx.App().Logger().Errorf("miss param: %s, method: %s, path: %s, ip: %s, request_id: %s", field.Name, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 				return
 			}
 
 			if len(param[0]) != field.Size {
 				ctx.Stopped()
 				ctx.SetHTTPCode(http.StatusBadRequest)
-				ctx.App().Logger().Errorf("field size wrong, field.name: %s, required size: %d, current siz: %d, method: %s, path: %s, ip: %s", field.Name, field.Size, len(param[0]), ctx.Method(), ctx.Path(), ctx.IP())
+				ctx.App().Logger().Errorf("field size wrong, field.name: %s, required size: %d, current siz: %d, method: %s, path: %s, ip: %s, request_id: %s", field.Name, field.Size, len(param[0]), ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 				return
 			}
 		}
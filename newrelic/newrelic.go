@@ -4,6 +4,9 @@ package newrelic
 import (
 	newrelic "github.com/newrelic/go-agent/v3/newrelic"
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/recovery"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New newrelic 监控
@@ -30,6 +33,19 @@ func New(appname string, license string) zeroapi.Handler {
 		ctx.Response().SetWriter(w)
 
 		ctx.AppendEnd(func() error {
+			if id := requestid.Get(ctx); id != "" {
+				txn.AddAttribute("request_id", id)
+			}
+
+			// 被 recovery 捕获的异常
+			if p := recovery.From(ctx); p != nil {
+				txn.NoticeError(newrelic.Error{
+					Message:    p.Error(),
+					Class:      "panic",
+					Attributes: map[string]interface{}{"stack": string(p.Stack)},
+				})
+			}
+
 			txn.End()
 			return nil
 		})
@@ -8,6 +8,9 @@ import (
 	zeroapi "github.com/zerogo-hub/zero-api"
 	zerobytes "github.com/zerogo-hub/zero-helper/bytes"
 	zerocache "github.com/zerogo-hub/zero-helper/cache"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 判断 nonce 是否有效，在一段时间内其不可重复
@@ -33,7 +36,7 @@ func New(cache zerocache.Cache, opts ...Option) zeroapi.Handler {
 		b.WriteByte(':')
 		b.Write(zerobytes.StringToBytes(s))
 		b.WriteByte(':')
-		b.Write(zerobytes.StringToBytes(ctx.IP()))
+		b.Write(zerobytes.StringToBytes(realip.IP(ctx)))
 
 		key := b.String()
 
@@ -41,12 +44,12 @@ func New(cache zerocache.Cache, opts ...Option) zeroapi.Handler {
 		if exist {
 			ctx.Stopped()
 			ctx.SetHTTPCode(http.StatusBadRequest)
-			ctx.App().Logger().Errorf("repeated nonce, method: %s, path: %s, ip: %s", ctx.Method(), ctx.Path(), ctx.IP())
+			ctx.App().Logger().Errorf("repeated nonce, method: %s, path: %s, ip: %s, request_id: %s", ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 			return
 		}
 
 		if err := cache.SetEx(key, "1", opt.Expire); err != nil {
-			ctx.App().Logger().Errorf("cache nonce failed, err: %s", err.Error())
+			ctx.App().Logger().Errorf("cache nonce failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 		}
 	}
 }
@@ -7,6 +7,9 @@ import (
 	"github.com/opentracing/opentracing-go/ext"
 	zeroapi "github.com/zerogo-hub/zero-api"
 
+	"github.com/zerogo-hub/zero-api-middleware/recovery"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+
 	jaeger "github.com/uber/jaeger-client-go"
 	jaegerConfig "github.com/uber/jaeger-client-go/config"
 )
@@ -42,6 +45,18 @@ func New(serviceName, addr string, app zeroapi.App) zeroapi.Handler {
 		ext.HTTPUrl.Set(span, ctx.Path())
 
 		ctx.AppendEnd(func() error {
+			ext.HTTPStatusCode.Set(span, uint16(ctx.HTTPCode()))
+
+			if id := requestid.Get(ctx); id != "" {
+				span.SetTag("request_id", id)
+			}
+
+			// 被 recovery 捕获的异常
+			if p := recovery.From(ctx); p != nil {
+				ext.Error.Set(span, true)
+				span.LogKV("event", "error", "error.kind", "panic", "message", p.Error(), "stack", string(p.Stack))
+			}
+
 			span.Finish()
 			return nil
 		})
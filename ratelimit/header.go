@@ -0,0 +1,135 @@
+// Package ratelimit 限流中间件共用的响应头
+package ratelimit
+
+import (
+	"math"
+	"strconv"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Style 限流响应头的格式
+type Style int
+
+const (
+	// StyleLegacy X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset
+	StyleLegacy Style = iota
+
+	// StyleIETF RateLimit, RateLimit-Policy
+	// https://datatracker.ietf.org/doc/draft-ietf-httpapi-ratelimit-headers/
+	StyleIETF
+
+	// StyleBoth 同时设置 StyleLegacy 与 StyleIETF
+	StyleBoth
+
+	// StyleNone 不设置任何限流响应头，包括 Retry-After
+	StyleNone
+)
+
+// Result 一次限流检查的结果，值小于 0 表示不适用
+type Result struct {
+	// Limited 是否被限制
+	Limited bool
+
+	// Limit 最大访问次数
+	Limit int
+
+	// Remaining 剩余访问次数
+	Remaining int
+
+	// Reset 时间过后，访问次数重置
+	Reset time.Duration
+
+	// RetryAfter 需要等待多长时间之后才能继续发送请求
+	RetryAfter time.Duration
+
+	// Quota 配额，在 Window 时间内允许的请求个数，用于 RateLimit-Policy
+	Quota int
+
+	// Window 配额的时间窗口，用于 RateLimit-Policy
+	Window time.Duration
+}
+
+// Writer 设置限流响应头
+type Writer struct {
+	// Style 响应头的格式，默认 StyleLegacy
+	Style Style
+
+	// RetryAfterOnLimited 是否只在被限制时设置 Retry-After
+	RetryAfterOnLimited bool
+
+	// PolicyName RateLimit 与 RateLimit-Policy 中的策略名称，默认 "default"
+	PolicyName string
+}
+
+// Write 根据限流结果设置响应头
+func (w *Writer) Write(ctx zeroapi.Context, result Result) {
+	switch w.Style {
+	case StyleNone:
+		return
+	case StyleLegacy:
+		w.writeLegacy(ctx, result)
+	case StyleIETF:
+		w.writeIETF(ctx, result)
+	case StyleBoth:
+		w.writeLegacy(ctx, result)
+		w.writeIETF(ctx, result)
+	}
+
+	if v := result.RetryAfter; v >= 0 && (result.Limited || !w.RetryAfterOnLimited) {
+		// 需要等待多长时间之后才能继续发送请求
+		ctx.SetHeader("Retry-After", strconv.Itoa(seconds(v)))
+	}
+}
+
+func (w *Writer) writeLegacy(ctx zeroapi.Context, result Result) {
+	if v := result.Limit; v >= 0 {
+		// 最大访问次数
+		ctx.SetHeader("X-RateLimit-Limit", strconv.Itoa(v))
+	}
+
+	if v := result.Remaining; v >= 0 {
+		// 剩余访问次数
+		ctx.SetHeader("X-RateLimit-Remaining", strconv.Itoa(v))
+	}
+
+	if v := result.Reset; v >= 0 {
+		// 时间过后，访问次数重置
+		ctx.SetHeader("X-RateLimit-Reset", strconv.Itoa(seconds(v)))
+	}
+}
+
+// writeIETF 结构化字段，如
+//
+// RateLimit-Policy: "default";q=100;w=60
+//
+// RateLimit: "default";r=50;t=30
+func (w *Writer) writeIETF(ctx zeroapi.Context, result Result) {
+	name := strconv.Quote(w.policyName())
+
+	if result.Quota >= 0 && result.Window > 0 {
+		ctx.SetHeader("RateLimit-Policy", name+";q="+strconv.Itoa(result.Quota)+";w="+strconv.Itoa(seconds(result.Window)))
+	}
+
+	if result.Remaining >= 0 {
+		v := name + ";r=" + strconv.Itoa(result.Remaining)
+		if result.Reset >= 0 {
+			v += ";t=" + strconv.Itoa(seconds(result.Reset))
+		}
+		ctx.SetHeader("RateLimit", v)
+	}
+}
+
+func (w *Writer) policyName() string {
+	if w.PolicyName == "" {
+		return "default"
+	}
+
+	return w.PolicyName
+}
+
+// seconds 向上取整
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"os"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamrealip "github.com/zerogo-hub/zero-api-middleware/realip"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("ip: %s, `ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", zamrealip.IP(ctx), pid, pid)
+}
+
+func main() {
+	a := app.New()
+
+	// 只信任本机与内网的代理，应放在其它中间件之前
+	a.Use(zamrealip.New(&zamrealip.Config{
+		TrustedProxies: []string{"127.0.0.1", "10.0.0.0/8"},
+	}))
+
+	a.Get("/", helloworldHandle)
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
+
+// 来自可信代理，使用 X-Forwarded-For 中第一个不可信的地址
+// 命令: curl -i -H "X-Forwarded-For: 1.2.3.4, 10.0.0.2" http://127.0.0.1:8877
+// 返回: ip: 1.2.3.4, ...
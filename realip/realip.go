@@ -0,0 +1,216 @@
+// Package realip 根据可信代理解析客户端的真实 ip
+//
+// 只有当请求来自可信代理时，才会使用代理设置的请求头，避免客户端伪造 ip
+// 解析结果保存在 ctx 中，本仓库的其它中间件通过 IP 获取客户端 ip
+package realip
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+const (
+	// Key 客户端 ip 在 ctx 中的 key
+	Key = "zam_realip"
+
+	// TrustedKey 连接的对端是否为可信代理，在 ctx 中的 key
+	TrustedKey = "zam_realip_trusted"
+)
+
+// Config 配置
+type Config struct {
+	// TrustedProxies 可信代理，可以是 ip 或者 CIDR，如 "10.0.0.0/8", "127.0.0.1"
+	// 为空时不信任任何代理，客户端 ip 为连接的对端地址
+	TrustedProxies []string
+
+	// Header 代理设置的请求头，默认为 X-Forwarded-For，可选 Forwarded、X-Real-IP
+	// 只使用这一个请求头，需与代理实际设置的请求头一致，其它请求头可能由客户端伪造
+	Header string
+}
+
+// resolver 解析客户端 ip
+type resolver struct {
+	trusted []*net.IPNet
+	header  string
+}
+
+// New 解析客户端的真实 ip，并保存到 ctx 中，应放在其它中间件之前
+//
+// config 为 nil 时不信任任何代理，TrustedProxies 无效时 panic
+func New(config *Config) zeroapi.Handler {
+	r := newResolver(config)
+
+	return func(ctx zeroapi.Context) {
+		req := ctx.Request()
+		ctx.SetValue(TrustedKey, r.trustedRemote(req))
+		ctx.SetValue(Key, r.resolve(req))
+	}
+}
+
+func newResolver(config *Config) *resolver {
+	r := &resolver{
+		header: "X-Forwarded-For",
+	}
+
+	if config != nil {
+		for _, proxy := range config.TrustedProxies {
+			r.trusted = append(r.trusted, parseCIDR(proxy))
+		}
+
+		if len(config.Header) > 0 {
+			r.header = config.Header
+		}
+	}
+
+	return r
+}
+
+// IP 获取客户端 ip，未使用 realip 中间件时返回 ctx.IP()
+func IP(ctx zeroapi.Context) string {
+	if ip, ok := ctx.Value(Key).(string); ok && len(ip) > 0 {
+		return ip
+	}
+
+	return ctx.IP()
+}
+
+// Trusted 连接的对端(RemoteAddr)是否为 TrustedProxies 中的可信代理，未使用 realip 中间件时返回 false
+//
+// 只检查连接的对端，不读取任何请求头，可用于决定是否信任代理设置的其它请求头，如 X-Request-ID
+func Trusted(ctx zeroapi.Context) bool {
+	trusted, _ := ctx.Value(TrustedKey).(bool)
+	return trusted
+}
+
+// parseCIDR 解析 ip 或者 CIDR
+func parseCIDR(s string) *net.IPNet {
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			panic("invalid trusted proxy: " + s)
+		}
+
+		bits := 32
+		if ip.To4() == nil {
+			bits = 128
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}
+	}
+
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic("invalid trusted proxy: " + s)
+	}
+
+	return n
+}
+
+func (r *resolver) isTrusted(ip net.IP) bool {
+	for _, n := range r.trusted {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// trustedRemote 连接的对端是否为可信代理
+func (r *resolver) trustedRemote(req *http.Request) bool {
+	ip := net.ParseIP(remoteIP(req.RemoteAddr))
+	return ip != nil && r.isTrusted(ip)
+}
+
+// resolve 连接的对端不是可信代理时，直接使用对端地址
+// 否则从右向左遍历代理链，跳过可信代理，第一个不可信的地址即为客户端 ip
+func (r *resolver) resolve(req *http.Request) string {
+	remote := remoteIP(req.RemoteAddr)
+
+	if !r.trustedRemote(req) {
+		return remote
+	}
+
+	var chain []string
+
+	switch http.CanonicalHeaderKey(r.header) {
+	case "Forwarded":
+		chain = forwardedFor(req.Header.Values(r.header))
+	case "X-Forwarded-For":
+		chain = forwardedList(req.Header.Values(r.header))
+	default:
+		if v := strings.TrimSpace(req.Header.Get(r.header)); len(v) > 0 {
+			chain = []string{v}
+		}
+	}
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		ip := net.ParseIP(chain[i])
+		if ip == nil {
+			// 无法解析时不再信任该值以及更左侧的值
+			return remote
+		}
+
+		if i == 0 || !r.isTrusted(ip) {
+			return ip.String()
+		}
+	}
+
+	return remote
+}
+
+// remoteIP 去除端口
+func remoteIP(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+
+	return host
+}
+
+// forwardedList 解析 X-Forwarded-For: client, proxy1, proxy2
+func forwardedList(values []string) []string {
+	chain := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			if v = strings.TrimSpace(v); len(v) > 0 {
+				chain = append(chain, v)
+			}
+		}
+	}
+
+	return chain
+}
+
+// forwardedFor 解析 Forwarded: for=192.0.2.60;proto=http, for="[2001:db8:cafe::17]:4711"
+// https://www.rfc-editor.org/rfc/rfc7239
+func forwardedFor(values []string) []string {
+	chain := make([]string, 0, len(values))
+	for _, value := range values {
+		for _, element := range strings.Split(value, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok || !strings.EqualFold(k, "for") {
+					continue
+				}
+
+				v = strings.Trim(v, `"`)
+				if strings.HasPrefix(v, "[") {
+					// [ipv6]:port
+					if end := strings.Index(v, "]"); end > 0 {
+						v = v[1:end]
+					}
+				} else if host, _, err := net.SplitHostPort(v); err == nil {
+					v = host
+				}
+
+				chain = append(chain, v)
+			}
+		}
+	}
+
+	return chain
+}
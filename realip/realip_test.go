@@ -0,0 +1,199 @@
+package realip
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	trusted := []string{"10.0.0.0/8", "127.0.0.1"}
+
+	tests := []struct {
+		name    string
+		config  *Config
+		remote  string
+		headers map[string][]string
+		want    string
+	}{
+		{
+			name:   "untrusted remote ignores headers",
+			config: &Config{TrustedProxies: trusted},
+			remote: "1.1.1.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"2.2.2.2"},
+			},
+			want: "1.1.1.1",
+		},
+		{
+			name:   "no trusted proxies",
+			config: nil,
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"2.2.2.2"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:   "x-forwarded-for single",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"2.2.2.2"},
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name:   "x-forwarded-for skips trusted proxies from right",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"3.3.3.3, 2.2.2.2, 10.0.0.2"},
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name:   "x-forwarded-for multiple header lines",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"3.3.3.3", "2.2.2.2, 10.0.0.2"},
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name:   "x-forwarded-for all trusted uses leftmost",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"10.0.0.3, 10.0.0.2"},
+			},
+			want: "10.0.0.3",
+		},
+		{
+			name:   "forged forwarded ignored by default",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=1.2.3.4"},
+				"X-Forwarded-For": {"2.2.2.2"},
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name:   "forged x-real-ip ignored by default",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-Ip": {"1.2.3.4"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:   "unparsable entry falls back to remote",
+			config: &Config{TrustedProxies: trusted},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Forwarded-For": {"1.2.3.4, unknown"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:   "unparsable entry does not fall through to other headers",
+			config: &Config{TrustedProxies: trusted, Header: "Forwarded"},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded":       {"for=unknown"},
+				"X-Forwarded-For": {"1.2.3.4"},
+			},
+			want: "10.0.0.1",
+		},
+		{
+			name:   "forwarded with port and ipv6",
+			config: &Config{TrustedProxies: trusted, Header: "Forwarded"},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded": {`for=192.0.2.60:8080;proto=http, for="[2001:db8:cafe::17]:4711"`},
+			},
+			want: "2001:db8:cafe::17",
+		},
+		{
+			name:   "forwarded skips trusted proxies",
+			config: &Config{TrustedProxies: trusted, Header: "Forwarded"},
+			remote: "10.0.0.1:1234",
+			headers: map[string][]string{
+				"Forwarded": {"for=192.0.2.60, for=10.0.0.2"},
+			},
+			want: "192.0.2.60",
+		},
+		{
+			name:   "x-real-ip when configured",
+			config: &Config{TrustedProxies: trusted, Header: "X-Real-IP"},
+			remote: "127.0.0.1:1234",
+			headers: map[string][]string{
+				"X-Real-Ip": {" 2.2.2.2 "},
+			},
+			want: "2.2.2.2",
+		},
+		{
+			name:    "trusted remote without header",
+			config:  &Config{TrustedProxies: trusted},
+			remote:  "10.0.0.1:1234",
+			headers: nil,
+			want:    "10.0.0.1",
+		},
+		{
+			name:    "remote without port",
+			config:  &Config{TrustedProxies: trusted},
+			remote:  "1.1.1.1",
+			headers: nil,
+			want:    "1.1.1.1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newResolver(tt.config)
+
+			req := &http.Request{RemoteAddr: tt.remote, Header: http.Header(tt.headers)}
+			if req.Header == nil {
+				req.Header = http.Header{}
+			}
+
+			if got := r.resolve(req); got != tt.want {
+				t.Errorf("resolve() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCIDRPanics(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("invalid trusted proxy should panic")
+		}
+	}()
+
+	parseCIDR("not-an-ip")
+}
+
+func TestTrustedRemote(t *testing.T) {
+	r := newResolver(&Config{TrustedProxies: []string{"10.0.0.0/8", "127.0.0.1"}})
+
+	tests := []struct {
+		remote string
+		want   bool
+	}{
+		{remote: "127.0.0.1:1234", want: true},
+		{remote: "10.1.2.3:1234", want: true},
+		{remote: "1.2.3.4:1234", want: false},
+		{remote: "unknown", want: false},
+	}
+
+	for _, tt := range tests {
+		// 请求头不影响结果
+		req := &http.Request{RemoteAddr: tt.remote, Header: http.Header{"X-Forwarded-For": {"10.0.0.1"}}}
+		if got := r.trustedRemote(req); got != tt.want {
+			t.Errorf("trustedRemote(%q) = %v, want %v", tt.remote, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamlogger "github.com/zerogo-hub/zero-api-middleware/logger"
+	zamrecovery "github.com/zerogo-hub/zero-api-middleware/recovery"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", pid, pid)
+}
+
+func hellopanic(ctx zeroapi.Context) {
+	panic("hello panic")
+}
+
+func hellodefer(ctx zeroapi.Context) {
+	defer r.Recover(ctx)
+
+	var m map[string]int
+	m["panic"] = 1
+}
+
+var r = zamrecovery.New(&zamrecovery.Config{
+	OnPanic: func(ctx zeroapi.Context, p *zamrecovery.Panic) {
+		// 发送告警
+	},
+})
+
+func main() {
+	a := app.New()
+
+	// 请求日志中增加 panic 字段，并使用 Error 级别打印
+	a.Use(zamlogger.New())
+
+	a.Get("/", helloworldHandle)
+
+	// curl http://127.0.0.1:8877/panic
+	// {"code":"500","message":"internal server error"}
+	a.Get("/panic", r.Wrap(hellopanic))
+	a.Get("/defer", hellodefer)
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
@@ -0,0 +1,148 @@
+// Package recovery 捕获处理函数中的异常(`panic`)，返回 500，记录调用栈并调用用户的回调
+//
+// zero-api 依次调用处理函数，中间件无法包裹后续的处理函数，需要使用 Wrap 包裹处理函数，
+// 或者在处理函数中使用 defer r.Recover(ctx)
+// 捕获的异常保存在 ctx 中，logger、opentracing 与 newrelic 通过 From 获取，并将请求标记为错误
+package recovery
+
+import (
+	"fmt"
+	"net/http"
+	"runtime"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// Key 捕获的异常在 ctx 中的 key
+const Key = "zam_recovery"
+
+// Panic 捕获的异常
+type Panic struct {
+	// Value panic 的参数
+	Value interface{}
+
+	// Stack 发生异常的 goroutine 的调用栈
+	Stack []byte
+
+	// Time 发生异常的时间
+	Time time.Time
+}
+
+// Error 实现 error
+func (p *Panic) Error() string {
+	if err, ok := p.Value.(error); ok {
+		return "panic: " + err.Error()
+	}
+
+	return fmt.Sprintf("panic: %v", p.Value)
+}
+
+// Unwrap panic 的参数为 error 时返回该 error
+func (p *Panic) Unwrap() error {
+	err, _ := p.Value.(error)
+	return err
+}
+
+// Config 配置
+type Config struct {
+	// StackSize 记录的调用栈的最大字节数，默认 8192，< 0 时不记录调用栈
+	StackSize int
+
+	// Handler 发生异常时的响应，默认返回 500
+	Handler func(ctx zeroapi.Context, p *Panic)
+
+	// OnPanic 发生异常时调用，用于告警，在 Handler 之前调用
+	OnPanic func(ctx zeroapi.Context, p *Panic)
+
+	// DisableLog 是否不使用 app 的日志打印异常与调用栈
+	DisableLog bool
+}
+
+// Recoverer 捕获异常
+type Recoverer struct {
+	config *Config
+}
+
+// New 创建 Recoverer
+func New(config ...*Config) *Recoverer {
+	c := &Config{}
+	if len(config) > 0 && config[0] != nil {
+		*c = *config[0]
+	}
+
+	if c.StackSize == 0 {
+		c.StackSize = 8192
+	}
+
+	if c.Handler == nil {
+		c.Handler = DefaultHandler
+	}
+
+	return &Recoverer{config: c}
+}
+
+var defaultRecoverer = New()
+
+// Wrap 使用默认配置包裹处理函数
+func Wrap(h zeroapi.Handler) zeroapi.Handler {
+	return defaultRecoverer.Wrap(h)
+}
+
+// Wrap 包裹处理函数，捕获其中的异常
+func (r *Recoverer) Wrap(h zeroapi.Handler) zeroapi.Handler {
+	return func(ctx zeroapi.Context) {
+		defer r.Recover(ctx)
+		h(ctx)
+	}
+}
+
+// Recover 捕获异常，需要在处理函数中直接 defer 调用，如 defer r.Recover(ctx)
+//
+// http.ErrAbortHandler 不会被捕获，由 net/http 中断连接
+func (r *Recoverer) Recover(ctx zeroapi.Context) {
+	v := recover()
+	if v == nil {
+		return
+	}
+
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+
+	p := &Panic{Value: v, Time: time.Now()}
+	if r.config.StackSize > 0 {
+		stack := make([]byte, r.config.StackSize)
+		p.Stack = stack[:runtime.Stack(stack, false)]
+	}
+
+	ctx.SetValue(Key, p)
+
+	if !r.config.DisableLog {
+		ctx.App().Logger().Errorf("%s, method: %s, path: %s, ip: %s, request_id: %s\n%s", p.Error(), ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx), p.Stack)
+	}
+
+	if r.config.OnPanic != nil {
+		r.config.OnPanic(ctx, p)
+	}
+
+	r.config.Handler(ctx, p)
+}
+
+// From 获取 ctx 中捕获的异常，未发生异常时返回 nil
+func From(ctx zeroapi.Context) *Panic {
+	p, _ := ctx.Value(Key).(*Panic)
+	return p
+}
+
+// DefaultHandler 默认的发生异常时的响应
+func DefaultHandler(ctx zeroapi.Context, p *Panic) {
+	ctx.SetHTTPCode(http.StatusInternalServerError)
+	if _, err := ctx.Message(http.StatusInternalServerError, "internal server error"); err != nil {
+		ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+	}
+	ctx.Stopped()
+}
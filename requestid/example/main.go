@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamlogger "github.com/zerogo-hub/zero-api-middleware/logger"
+	zamrealip "github.com/zerogo-hub/zero-api-middleware/realip"
+	zamrequestid "github.com/zerogo-hub/zero-api-middleware/requestid"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart, request id: %s", pid, pid, zamrequestid.Get(ctx))
+}
+
+func main() {
+	a := app.New()
+
+	// 只信任来自网关(本机与内网的可信代理)的请求 id，其它请求总是生成新的 id
+	a.Use(zamrealip.New(&zamrealip.Config{TrustedProxies: []string{"127.0.0.1", "10.0.0.0/8"}}))
+	a.Use(zamrequestid.New(&zamrequestid.Config{
+		Trust: func(ctx zeroapi.Context) bool {
+			return zamrealip.Trusted(ctx)
+		},
+		Generator: zamrequestid.UUIDv7,
+	}))
+
+	// 请求日志中输出请求 id
+	a.Use(zamlogger.New(&zamlogger.Config{IP: true, Code: true, Cost: true, RequestID: true}))
+
+	// 本机的请求来自可信代理，沿用请求中的 id
+	// curl -i -H "X-Request-ID: 01HZX3Q7E8N6W1K2J4M5P6R7S8" http://127.0.0.1:8877
+	// X-Request-Id: 01HZX3Q7E8N6W1K2J4M5P6R7S8
+	// 来自其它地址的请求，返回新生成的 id
+	a.Get("/", helloworldHandle)
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
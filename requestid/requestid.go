@@ -0,0 +1,166 @@
+// Package requestid 为每一个请求生成唯一的 id，设置到响应头并保存在 ctx 中
+//
+// 配置 Trust 后，可信的请求可以携带 X-Request-ID，校验通过时沿用该值，便于跨服务关联日志
+// 本仓库的其它中间件在日志中通过 Get 输出请求 id
+package requestid
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+)
+
+// Key 请求 id 在 ctx 中的 key
+const Key = "zam_requestid"
+
+// Config 配置
+type Config struct {
+	// Header 请求头与响应头的名称，默认 X-Request-ID
+	Header string
+
+	// Trust 是否信任请求中携带的 id，为 nil 时不信任任何请求，总是生成新的 id
+	// 可以只信任来自网关的请求，避免客户端伪造 id 干扰日志关联
+	Trust func(ctx zeroapi.Context) bool
+
+	// Validate 校验请求中携带的 id，默认为 Valid
+	Validate func(id string) bool
+
+	// Generator 生成 id，默认为 ULID，可选 UUIDv7
+	Generator func() string
+}
+
+// New 设置请求 id，应放在其它中间件之前
+func New(config ...*Config) zeroapi.Handler {
+	c := &Config{}
+	if len(config) > 0 && config[0] != nil {
+		*c = *config[0]
+	}
+
+	if c.Header == "" {
+		c.Header = "X-Request-ID"
+	}
+
+	if c.Validate == nil {
+		c.Validate = Valid
+	}
+
+	if c.Generator == nil {
+		c.Generator = ULID
+	}
+
+	return func(ctx zeroapi.Context) {
+		id := ctx.Header(c.Header)
+		if id == "" || c.Trust == nil || !c.Trust(ctx) || !c.Validate(id) {
+			id = c.Generator()
+		}
+
+		ctx.SetValue(Key, id)
+		ctx.SetHeader(c.Header, id)
+	}
+}
+
+// Get 获取请求 id，未使用 requestid 中间件时返回空字符串
+func Get(ctx zeroapi.Context) string {
+	id, _ := ctx.Value(Key).(string)
+	return id
+}
+
+// Valid 默认的校验，长度不超过 128，只允许字母、数字与 "-_.:"
+func Valid(id string) bool {
+	if len(id) == 0 || len(id) > 128 {
+		return false
+	}
+
+	for i := 0; i < len(id); i++ {
+		c := id[i]
+		switch {
+		case c >= '0' && c <= '9', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// crockford ULID 使用的 base32 字母表
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ULID 生成 ULID，48 位毫秒时间戳与 80 位随机数，26 个字符，按时间排序
+func ULID() string {
+	var b [16]byte
+	putTime(b[:], time.Now())
+	random(b[6:])
+
+	var s [26]byte
+	// 128 位按 5 位分组，第一个字符只使用 3 位
+	s[0] = crockford[(b[0]&224)>>5]
+	s[1] = crockford[b[0]&31]
+	s[2] = crockford[(b[1]&248)>>3]
+	s[3] = crockford[((b[1]&7)<<2)|((b[2]&192)>>6)]
+	s[4] = crockford[(b[2]&62)>>1]
+	s[5] = crockford[((b[2]&1)<<4)|((b[3]&240)>>4)]
+	s[6] = crockford[((b[3]&15)<<1)|((b[4]&128)>>7)]
+	s[7] = crockford[(b[4]&124)>>2]
+	s[8] = crockford[((b[4]&3)<<3)|((b[5]&224)>>5)]
+	s[9] = crockford[b[5]&31]
+	s[10] = crockford[(b[6]&248)>>3]
+	s[11] = crockford[((b[6]&7)<<2)|((b[7]&192)>>6)]
+	s[12] = crockford[(b[7]&62)>>1]
+	s[13] = crockford[((b[7]&1)<<4)|((b[8]&240)>>4)]
+	s[14] = crockford[((b[8]&15)<<1)|((b[9]&128)>>7)]
+	s[15] = crockford[(b[9]&124)>>2]
+	s[16] = crockford[((b[9]&3)<<3)|((b[10]&224)>>5)]
+	s[17] = crockford[b[10]&31]
+	s[18] = crockford[(b[11]&248)>>3]
+	s[19] = crockford[((b[11]&7)<<2)|((b[12]&192)>>6)]
+	s[20] = crockford[(b[12]&62)>>1]
+	s[21] = crockford[((b[12]&1)<<4)|((b[13]&240)>>4)]
+	s[22] = crockford[((b[13]&15)<<1)|((b[14]&128)>>7)]
+	s[23] = crockford[(b[14]&124)>>2]
+	s[24] = crockford[((b[14]&3)<<3)|((b[15]&224)>>5)]
+	s[25] = crockford[b[15]&31]
+
+	return string(s[:])
+}
+
+// UUIDv7 生成 RFC 9562 中的 UUID version 7，48 位毫秒时间戳与 74 位随机数，按时间排序
+func UUIDv7() string {
+	var b [16]byte
+	putTime(b[:], time.Now())
+	random(b[6:])
+
+	b[6] = (b[6] & 0x0f) | 0x70
+	b[8] = (b[8] & 0x3f) | 0x80
+
+	var s [36]byte
+	hex.Encode(s[0:8], b[0:4])
+	s[8] = '-'
+	hex.Encode(s[9:13], b[4:6])
+	s[13] = '-'
+	hex.Encode(s[14:18], b[6:8])
+	s[18] = '-'
+	hex.Encode(s[19:23], b[8:10])
+	s[23] = '-'
+	hex.Encode(s[24:], b[10:])
+
+	return string(s[:])
+}
+
+// putTime 将毫秒时间戳写入前 6 个字节
+func putTime(b []byte, t time.Time) {
+	var ms [8]byte
+	binary.BigEndian.PutUint64(ms[:], uint64(t.UnixMilli()))
+	copy(b[:6], ms[2:])
+}
+
+// random 填充随机数，crypto/rand 失败时使用纳秒时间戳
+func random(b []byte) {
+	if _, err := rand.Read(b); err != nil {
+		binary.BigEndian.PutUint64(b[len(b)-8:], uint64(time.Now().UnixNano()))
+	}
+}
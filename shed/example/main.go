@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamshed "github.com/zerogo-hub/zero-api-middleware/shed"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", pid, pid)
+}
+
+func main() {
+	a := app.New()
+
+	s := zamshed.New(&zamshed.Config{
+		MaxGoroutines: 10000,
+		MaxHeapBytes:  1 << 30,
+		MaxP99:        500 * time.Millisecond,
+		PriorityFunc: func(ctx zeroapi.Context) zamshed.Priority {
+			// 健康检查与管理接口从不拒绝
+			if ctx.Path() == "/health" || strings.HasPrefix(ctx.Path(), "/admin") {
+				return zamshed.PriorityCritical
+			}
+			// 报表等可延后的请求优先拒绝
+			if strings.HasPrefix(ctx.Path(), "/report") {
+				return zamshed.PriorityLow
+			}
+			return zamshed.PriorityNormal
+		},
+	})
+	defer s.Stop()
+
+	a.Use(s.Handle)
+
+	a.Get("/", helloworldHandle)
+	a.Get("/health", helloworldHandle)
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
@@ -0,0 +1,356 @@
+// Package shed 过载保护，进程过载时拒绝低优先级的请求
+//
+// 过载信号包括协程个数、GC 暂停时间、堆内存、本中间件统计的 p99 耗时以及请求的排队时间
+package shed
+
+import (
+	"math"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// Priority 请求的优先级
+type Priority int
+
+const (
+	// PriorityLow 低优先级，过载时最先被拒绝
+	PriorityLow Priority = iota
+	// PriorityNormal 普通优先级，严重过载时被拒绝
+	PriorityNormal
+	// PriorityCritical 关键请求，如健康检查、管理接口，从不拒绝
+	PriorityCritical
+)
+
+// Config 配置，阈值为 0 时不检查该信号
+type Config struct {
+	// PriorityFunc 为请求分配优先级，默认均为 PriorityNormal
+	PriorityFunc func(ctx zeroapi.Context) Priority
+
+	// MaxGoroutines 协程个数上限
+	MaxGoroutines int
+
+	// MaxGCPause 最近一次 GC 暂停时间的上限
+	MaxGCPause time.Duration
+
+	// MaxHeapBytes 堆内存上限，单位 bytes
+	MaxHeapBytes uint64
+
+	// MaxP99 最近请求耗时 p99 的上限
+	MaxP99 time.Duration
+
+	// MaxQueueTime 请求排队时间的上限，排队时间由代理设置的 QueueTimeHeader 计算
+	MaxQueueTime time.Duration
+
+	// QueueTimeHeader 代理接收到请求的时间，默认 "X-Request-Start"
+	// 支持 "t=1600000000.123"(秒)、毫秒、微秒时间戳
+	QueueTimeHeader string
+
+	// CriticalRatio 过载程度达到该值时，PriorityNormal 的请求也被拒绝，默认 1.5
+	// 过载程度为各个信号与其阈值比值的最大值，达到 1 时拒绝 PriorityLow 的请求
+	CriticalRatio float64
+
+	// SampleInterval 采样进程信号的间隔，默认 1 秒
+	SampleInterval time.Duration
+
+	// LatencyWindow 计算 p99 使用的最近请求个数，默认 1000
+	LatencyWindow int
+
+	// LatencyMaxAge 计算 p99 时忽略超过该时间的耗时，默认 10 秒
+	// 请求均被拒绝时没有新的耗时，旧的耗时过期后 p99 回落，不会一直拒绝
+	LatencyMaxAge time.Duration
+
+	// RejectCode 拒绝时的 HTTP Code，默认 503
+	RejectCode int
+}
+
+// Stats 过载统计
+type Stats struct {
+	// Goroutines 协程个数
+	Goroutines int `json:"goroutines"`
+	// GCPause 最近一次 GC 暂停时间
+	GCPause time.Duration `json:"gc_pause"`
+	// HeapBytes 堆内存
+	HeapBytes uint64 `json:"heap_bytes"`
+	// P99 最近请求耗时的 p99
+	P99 time.Duration `json:"p99"`
+	// Overload 过载程度，不考虑排队时间
+	Overload float64 `json:"overload"`
+	// Rejected 被拒绝的请求个数
+	Rejected uint64 `json:"rejected"`
+}
+
+// Shedder 过载保护
+//
+// 使用 a.Use(s.Handle) 注册为中间件，不再使用时调用 Stop
+type Shedder struct {
+	c *Config
+
+	// stats 后台采样的结果
+	lock  *sync.RWMutex
+	stats Stats
+
+	// latencies 最近请求的耗时，环形缓冲
+	latencyLock *sync.Mutex
+	latencies   []latency
+	next        int
+	full        bool
+
+	rejected atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// latency 请求的耗时以及记录的时间
+type latency struct {
+	d  time.Duration
+	at time.Time
+}
+
+func defaultConfig() *Config {
+	return &Config{
+		QueueTimeHeader: "X-Request-Start",
+		CriticalRatio:   1.5,
+		SampleInterval:  time.Second,
+		LatencyWindow:   1000,
+		LatencyMaxAge:   10 * time.Second,
+		RejectCode:      http.StatusServiceUnavailable,
+	}
+}
+
+// New 过载保护，并启动后台采样
+func New(config *Config) *Shedder {
+	c := defaultConfig()
+	if config != nil {
+		c.PriorityFunc = config.PriorityFunc
+		c.MaxGoroutines = config.MaxGoroutines
+		c.MaxGCPause = config.MaxGCPause
+		c.MaxHeapBytes = config.MaxHeapBytes
+		c.MaxP99 = config.MaxP99
+		c.MaxQueueTime = config.MaxQueueTime
+		if config.QueueTimeHeader != "" {
+			c.QueueTimeHeader = config.QueueTimeHeader
+		}
+		if config.CriticalRatio > 1 {
+			c.CriticalRatio = config.CriticalRatio
+		}
+		if config.SampleInterval > 0 {
+			c.SampleInterval = config.SampleInterval
+		}
+		if config.LatencyWindow > 0 {
+			c.LatencyWindow = config.LatencyWindow
+		}
+		if config.LatencyMaxAge > 0 {
+			c.LatencyMaxAge = config.LatencyMaxAge
+		}
+		if config.RejectCode > 0 {
+			c.RejectCode = config.RejectCode
+		}
+	}
+
+	s := &Shedder{
+		c:           c,
+		lock:        &sync.RWMutex{},
+		latencyLock: &sync.Mutex{},
+		latencies:   make([]latency, c.LatencyWindow),
+		stop:        make(chan struct{}),
+	}
+
+	s.sample()
+	go s.sampler()
+
+	return s
+}
+
+// Handle 中间件
+func (s *Shedder) Handle(ctx zeroapi.Context) {
+	// 与 throttle 相同，不处理预检请求
+	if ctx.Method() == http.MethodOptions {
+		return
+	}
+
+	priority := PriorityNormal
+	if s.c.PriorityFunc != nil {
+		priority = s.c.PriorityFunc(ctx)
+	}
+
+	if priority != PriorityCritical {
+		overload := s.overload(ctx)
+
+		if overload >= s.c.CriticalRatio || (priority == PriorityLow && overload >= 1) {
+			s.rejected.Add(1)
+			ctx.Stopped()
+			ctx.SetHTTPCode(s.c.RejectCode)
+			ctx.App().Logger().Errorf("load shedding, overload: %.2f, priority: %d, method: %s, path: %s, ip: %s, request_id: %s", overload, priority, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
+			return
+		}
+	}
+
+	start := time.Now()
+
+	ctx.AppendEnd(func() error {
+		s.record(time.Since(start))
+		return nil
+	})
+}
+
+// Stats 获取过载统计
+func (s *Shedder) Stats() Stats {
+	s.lock.RLock()
+	stats := s.stats
+	s.lock.RUnlock()
+
+	stats.Rejected = s.rejected.Load()
+	return stats
+}
+
+// Stop 停止后台采样，可重复调用
+func (s *Shedder) Stop() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// overload 过载程度，各个信号与其阈值比值的最大值
+func (s *Shedder) overload(ctx zeroapi.Context) float64 {
+	s.lock.RLock()
+	overload := s.stats.Overload
+	s.lock.RUnlock()
+
+	if s.c.MaxQueueTime > 0 {
+		if queueTime, ok := s.queueTime(ctx); ok {
+			overload = math.Max(overload, float64(queueTime)/float64(s.c.MaxQueueTime))
+		}
+	}
+
+	return overload
+}
+
+// queueTime 根据代理设置的请求头计算排队时间
+func (s *Shedder) queueTime(ctx zeroapi.Context) (time.Duration, bool) {
+	v := strings.TrimPrefix(ctx.Header(s.c.QueueTimeHeader), "t=")
+	if v == "" {
+		return 0, false
+	}
+
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil || f <= 0 {
+		return 0, false
+	}
+
+	// 根据数值大小判断单位: 秒、毫秒、微秒
+	var start time.Time
+	switch {
+	case f < 1e11:
+		start = time.Unix(0, int64(f*float64(time.Second)))
+	case f < 1e14:
+		start = time.UnixMilli(int64(f))
+	default:
+		start = time.UnixMicro(int64(f))
+	}
+
+	d := time.Since(start)
+	if d < 0 {
+		return 0, false
+	}
+
+	return d, true
+}
+
+// record 记录请求耗时
+func (s *Shedder) record(d time.Duration) {
+	s.latencyLock.Lock()
+	defer s.latencyLock.Unlock()
+
+	s.latencies[s.next] = latency{d: d, at: time.Now()}
+	s.next++
+	if s.next == len(s.latencies) {
+		s.next = 0
+		s.full = true
+	}
+}
+
+// p99 最近 LatencyMaxAge 内请求耗时的 p99
+func (s *Shedder) p99() time.Duration {
+	deadline := time.Now().Add(-s.c.LatencyMaxAge)
+
+	s.latencyLock.Lock()
+	n := s.next
+	if s.full {
+		n = len(s.latencies)
+	}
+	l := make([]time.Duration, 0, n)
+	for _, v := range s.latencies[:n] {
+		if v.at.After(deadline) {
+			l = append(l, v.d)
+		}
+	}
+	s.latencyLock.Unlock()
+
+	n = len(l)
+	if n == 0 {
+		return 0
+	}
+
+	sort.Slice(l, func(i, j int) bool { return l[i] < l[j] })
+
+	return l[int(math.Ceil(float64(n)*0.99))-1]
+}
+
+func (s *Shedder) sampler() {
+	ticker := time.NewTicker(s.c.SampleInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.sample()
+		case <-s.stop:
+			return
+		}
+	}
+}
+
+// sample 采样进程信号，并计算过载程度
+func (s *Shedder) sample() {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+
+	stats := Stats{
+		Goroutines: runtime.NumGoroutine(),
+		HeapBytes:  m.HeapAlloc,
+		P99:        s.p99(),
+	}
+
+	if m.NumGC > 0 {
+		stats.GCPause = time.Duration(m.PauseNs[(m.NumGC+255)%256])
+	}
+
+	if s.c.MaxGoroutines > 0 {
+		stats.Overload = math.Max(stats.Overload, float64(stats.Goroutines)/float64(s.c.MaxGoroutines))
+	}
+	if s.c.MaxGCPause > 0 {
+		stats.Overload = math.Max(stats.Overload, float64(stats.GCPause)/float64(s.c.MaxGCPause))
+	}
+	if s.c.MaxHeapBytes > 0 {
+		stats.Overload = math.Max(stats.Overload, float64(stats.HeapBytes)/float64(s.c.MaxHeapBytes))
+	}
+	if s.c.MaxP99 > 0 {
+		stats.Overload = math.Max(stats.Overload, float64(stats.P99)/float64(s.c.MaxP99))
+	}
+
+	s.lock.Lock()
+	s.stats = stats
+	s.lock.Unlock()
+}
@@ -9,6 +9,9 @@ import (
 
 	zeroapi "github.com/zerogo-hub/zero-api"
 	zerocrypto "github.com/zerogo-hub/zero-helper/crypto"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 签名验证
@@ -26,7 +29,7 @@ func New(secretKey string, opts ...Option) zeroapi.Handler {
 			if err := checkSign(opt.SignName, secretKey, ctx.QueryAll()); err != nil {
 				ctx.Stopped()
 				ctx.SetHTTPCode(http.StatusBadRequest)
-				ctx.App().Logger().Errorf("check sign failed, method: %s, path: %s, ip: %s", ctx.Method(), ctx.Path(), ctx.IP())
+				ctx.App().Logger().Errorf("check sign failed, method: %s, path: %s, ip: %s, request_id: %s", ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 			}
 		}
 	}
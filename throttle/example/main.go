@@ -20,6 +20,28 @@ func main() {
 		// 每分钟请求两次
 		PerMin: 2,
 		Burst:  1,
+
+		// 登录接口每秒 1 次且每天 100 次，其它请求使用 PerMin 与 Burst
+		// Rules: []*zamthrottle.Rule{
+		// 	{
+		// 		Method: http.MethodPost,
+		// 		Path:   "/login*",
+		// 		Quotas: []zamthrottle.Quota{
+		// 			zamthrottle.PerSec(1, 1),
+		// 			zamthrottle.PerDay(100, 10),
+		// 		},
+		// 	},
+		// },
+
+		// 多个副本共享限流状态，cache 使用 zerocache 创建
+		// Store:    zamthrottle.NewCacheStore(cache, "throttle:"),
+		// FailOpen: true,
+
+		// 使用 IETF 的 RateLimit 与 RateLimit-Policy，只在被限制时返回 Retry-After
+		// Header: &ratelimit.Writer{
+		// 	Style:               ratelimit.StyleIETF,
+		// 	RetryAfterOnLimited: true,
+		// },
 	}))
 
 	a.Get("/", helloworldHandle)
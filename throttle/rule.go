@@ -0,0 +1,199 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	throttled "github.com/throttled/throttled/v2"
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/ratelimit"
+)
+
+// Rule 路由规则，匹配的请求使用 Rule 中的配额
+type Rule struct {
+	// Method 请求方法，为空时匹配所有方法
+	Method string
+
+	// Path 路径，支持通配符 "*"，如 "/api/*"，为空时匹配所有路径
+	Path string
+
+	// Header 请求头需要满足的值，如 {"X-Plan": "free"}，值为 "*" 时只要求请求头存在
+	Header map[string]string
+
+	// Quotas 同时生效的多个配额，如每秒 10 个且每天 1000 个，任意一个被限制时请求被限制
+	Quotas []Quota
+
+	// path 将 Path 解析后存储于此
+	path *regexp.Regexp
+}
+
+// Quota 配额，每 Per 时间允许 Count 个请求，并允许额外的 Burst 个请求
+type Quota struct {
+	Count int
+	Per   time.Duration
+	Burst int
+}
+
+// PerSec 每秒 count 个请求
+func PerSec(count, burst int) Quota {
+	return Quota{Count: count, Per: time.Second, Burst: burst}
+}
+
+// PerMin 每分钟 count 个请求
+func PerMin(count, burst int) Quota {
+	return Quota{Count: count, Per: time.Minute, Burst: burst}
+}
+
+// PerHour 每小时 count 个请求
+func PerHour(count, burst int) Quota {
+	return Quota{Count: count, Per: time.Hour, Burst: burst}
+}
+
+// PerDay 每天 count 个请求
+func PerDay(count, burst int) Quota {
+	return Quota{Count: count, Per: 24 * time.Hour, Burst: burst}
+}
+
+func (q Quota) rateQuota() throttled.RateQuota {
+	return throttled.RateQuota{
+		MaxRate:  throttled.PerDuration(q.Count, q.Per),
+		MaxBurst: q.Burst,
+	}
+}
+
+// policy 一组同时生效的配额
+type policy struct {
+	rule     *Rule
+	quotas   []Quota
+	prefixes []string
+	limiters []*throttled.GCRARateLimiterCtx
+
+	// order 按时间窗口从短到长排列的配额下标
+	order []int
+}
+
+func newPolicy(store throttled.GCRAStoreCtx, rule *Rule, prefix string, quotas []Quota) (*policy, error) {
+	p := &policy{
+		rule:     rule,
+		quotas:   quotas,
+		prefixes: make([]string, 0, len(quotas)),
+		limiters: make([]*throttled.GCRARateLimiterCtx, 0, len(quotas)),
+		order:    make([]int, 0, len(quotas)),
+	}
+
+	for i, quota := range quotas {
+		if quota.Count <= 0 || quota.Per <= 0 {
+			return nil, errors.New("quota count and per must bigger than zero")
+		}
+
+		rateLimiter, err := throttled.NewGCRARateLimiterCtx(store, quota.rateQuota())
+		if err != nil {
+			return nil, err
+		}
+
+		// 不同配额在存储中使用不同的 key
+		p.prefixes = append(p.prefixes, prefix+"q"+strconv.Itoa(i)+"_")
+		p.limiters = append(p.limiters, rateLimiter)
+		p.order = append(p.order, i)
+	}
+
+	sort.SliceStable(p.order, func(i, j int) bool {
+		return quotas[p.order[i]].Per < quotas[p.order[j]].Per
+	})
+
+	// 默认配额沿用原有的 key
+	if prefix == "" && len(p.prefixes) == 1 {
+		p.prefixes[0] = ""
+	}
+
+	return p, nil
+}
+
+// rateLimit 按时间窗口从短到长检查每一个配额，遇到限制时立即返回
+//
+// 被短时间窗口限制的请求不会消耗更长时间窗口的配额，如被每秒的配额限制时，不消耗每天的配额
+// 均未限制时返回剩余次数最少的结果
+func (p *policy) rateLimit(ctx context.Context, key string) (bool, ratelimit.Result, error) {
+	limited := false
+	var result throttled.RateLimitResult
+	var quota Quota
+
+	for n, i := range p.order {
+		l, r, err := p.limiters[i].RateLimitCtx(ctx, p.prefixes[i]+key, 1)
+		if err != nil {
+			return false, ratelimit.Result{}, err
+		}
+
+		if n == 0 || l || stricter(r, result) {
+			result = r
+			quota = p.quotas[i]
+		}
+
+		if l {
+			limited = true
+			break
+		}
+	}
+
+	return limited, ratelimit.Result{
+		Limited:    limited,
+		Limit:      result.Limit,
+		Remaining:  result.Remaining,
+		Reset:      result.ResetAfter,
+		RetryAfter: result.RetryAfter,
+		Quota:      quota.Count,
+		Window:     quota.Per,
+	}, nil
+}
+
+// stricter 未限制的结果 a 是否比 b 更严格: 剩余次数少的更严格，相同时重置时间长的更严格
+func stricter(a, b throttled.RateLimitResult) bool {
+	if a.Remaining != b.Remaining {
+		return a.Remaining < b.Remaining
+	}
+
+	return a.ResetAfter > b.ResetAfter
+}
+
+func (rule *Rule) compile() {
+	if rule.Path == "" {
+		return
+	}
+
+	pattern := regexp.QuoteMeta(rule.Path)
+	pattern = strings.Replace(pattern, "\\*", ".*", -1)
+	rule.path = regexp.MustCompile("^" + pattern + "$")
+}
+
+// match 请求是否匹配该规则
+func (rule *Rule) match(ctx zeroapi.Context) bool {
+	if rule.Method != "" && !strings.EqualFold(rule.Method, ctx.Method()) {
+		return false
+	}
+
+	if rule.path != nil && !rule.path.MatchString(ctx.Path()) {
+		return false
+	}
+
+	for name, value := range rule.Header {
+		v := ctx.Header(name)
+		if value == "*" {
+			if v == "" {
+				return false
+			}
+			continue
+		}
+
+		if v != value {
+			return false
+		}
+	}
+
+	return true
+}
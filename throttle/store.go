@@ -0,0 +1,148 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	throttled "github.com/throttled/throttled/v2"
+	zerocache "github.com/zerogo-hub/zero-helper/cache"
+)
+
+// DoFunc 执行一条 redis 命令，返回值与 redigo 的 Conn.Do 一致
+//
+// zerocache.Cache.DO 以及各类兼容 redis 协议的客户端、测试用的内存替身均可适配
+type DoFunc func(cmd string, args ...interface{}) (interface{}, error)
+
+// redisCASScript 比较并交换，key 不存在时返回 0
+const redisCASScript = `
+local v = redis.call('get', KEYS[1])
+if v == false or v ~= ARGV[1] then
+  return 0
+end
+redis.call('setex', KEYS[1], ARGV[3], ARGV[2])
+return 1
+`
+
+// redisStore 基于 redis 协议的存储，多个进程共享同一份限流状态
+type redisStore struct {
+	do     DoFunc
+	prefix string
+}
+
+// NewRedisStore 基于 redis 协议的存储，多个副本共享限流状态
+//
+// prefix 为 key 的前缀，如 "throttle:"，依赖 redis 2.6+ 的 EVAL
+func NewRedisStore(do DoFunc, prefix string) throttled.GCRAStoreCtx {
+	return &redisStore{do: do, prefix: prefix}
+}
+
+// NewCacheStore 基于 zerocache.Cache 的存储
+func NewCacheStore(cache zerocache.Cache, prefix string) throttled.GCRAStoreCtx {
+	return NewRedisStore(cache.DO, prefix)
+}
+
+// GetWithTime 获取 key 的值以及 redis 服务器的时间，key 不存在时返回 -1
+func (s *redisStore) GetWithTime(ctx context.Context, key string) (int64, time.Time, error) {
+	var now time.Time
+
+	if err := ctx.Err(); err != nil {
+		return 0, now, err
+	}
+
+	reply, err := s.do("TIME")
+	if err != nil {
+		return 0, now, err
+	}
+
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != 2 {
+		return 0, now, fmt.Errorf("unexpected TIME reply: %v", reply)
+	}
+
+	sec, err := replyInt64(values[0], nil)
+	if err != nil {
+		return 0, now, err
+	}
+
+	usec, err := replyInt64(values[1], nil)
+	if err != nil {
+		return 0, now, err
+	}
+
+	now = time.Unix(sec, usec*int64(time.Microsecond))
+
+	v, err := replyInt64(s.do("GET", s.prefix+key))
+	if err == errNil {
+		return -1, now, nil
+	} else if err != nil {
+		return 0, now, err
+	}
+
+	return v, now, nil
+}
+
+// SetIfNotExistsWithTTL key 不存在时设置，并设置过期时间
+func (s *redisStore) SetIfNotExistsWithTTL(ctx context.Context, key string, value int64, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	reply, err := s.do("SET", s.prefix+key, value, "EX", ttlSeconds(ttl), "NX")
+	if err != nil {
+		return false, err
+	}
+
+	// 设置成功时返回 OK，key 已存在时返回 nil
+	return reply != nil, nil
+}
+
+// CompareAndSwapWithTTL key 的值等于 old 时设置为 new，并更新过期时间
+func (s *redisStore) CompareAndSwapWithTTL(ctx context.Context, key string, old, new int64, ttl time.Duration) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, err
+	}
+
+	v, err := replyInt64(s.do("EVAL", redisCASScript, 1, s.prefix+key, old, new, ttlSeconds(ttl)))
+	if err != nil {
+		return false, err
+	}
+
+	return v == 1, nil
+}
+
+// ttlSeconds EXPIRE 0 会立即删除 key，至少为 1 秒
+func ttlSeconds(ttl time.Duration) int {
+	seconds := int(ttl.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+
+	return seconds
+}
+
+var errNil = errors.New("nil reply")
+
+// replyInt64 将 redis 的返回值转为 int64，返回值为 nil 时返回 errNil
+func replyInt64(reply interface{}, err error) (int64, error) {
+	if err != nil {
+		return 0, err
+	}
+
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case nil:
+		return 0, errNil
+	case error:
+		return 0, v
+	}
+
+	return 0, fmt.Errorf("unexpected reply type: %T", reply)
+}
@@ -0,0 +1,310 @@
+package throttle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	throttled "github.com/throttled/throttled/v2"
+)
+
+// redisError 与 redigo 的 redis.Error 相同，命令出错时作为返回值而不是 err
+type redisError string
+
+func (e redisError) Error() string { return string(e) }
+
+// call 一次 redis 命令
+type call struct {
+	cmd  string
+	args []interface{}
+}
+
+// fakeDo 按命令返回固定的结果，并记录调用
+type fakeDo struct {
+	replies map[string]interface{}
+	errs    map[string]error
+	calls   []call
+}
+
+func (f *fakeDo) do(cmd string, args ...interface{}) (interface{}, error) {
+	f.calls = append(f.calls, call{cmd: cmd, args: args})
+	return f.replies[cmd], f.errs[cmd]
+}
+
+func timeReply(t time.Time) []interface{} {
+	return []interface{}{
+		[]byte(strconv.FormatInt(t.Unix(), 10)),
+		[]byte(strconv.FormatInt(int64(t.Nanosecond()/1000), 10)),
+	}
+}
+
+func TestGetWithTime(t *testing.T) {
+	now := time.Unix(1600000000, 123456000)
+	errConn := errors.New("connection refused")
+
+	tests := []struct {
+		name    string
+		replies map[string]interface{}
+		errs    map[string]error
+		want    int64
+		wantErr bool
+	}{
+		{
+			name:    "missing key",
+			replies: map[string]interface{}{"TIME": timeReply(now), "GET": nil},
+			want:    -1,
+		},
+		{
+			name:    "bytes value",
+			replies: map[string]interface{}{"TIME": timeReply(now), "GET": []byte("42")},
+			want:    42,
+		},
+		{
+			name:    "int value",
+			replies: map[string]interface{}{"TIME": timeReply(now), "GET": int64(42)},
+			want:    42,
+		},
+		{
+			name:    "invalid value",
+			replies: map[string]interface{}{"TIME": timeReply(now), "GET": []byte("abc")},
+			wantErr: true,
+		},
+		{
+			name:    "error value",
+			replies: map[string]interface{}{"TIME": timeReply(now), "GET": redisError("WRONGTYPE")},
+			wantErr: true,
+		},
+		{
+			name:    "get error",
+			replies: map[string]interface{}{"TIME": timeReply(now)},
+			errs:    map[string]error{"GET": errConn},
+			wantErr: true,
+		},
+		{
+			name:    "time error",
+			errs:    map[string]error{"TIME": errConn},
+			wantErr: true,
+		},
+		{
+			name:    "time nil",
+			replies: map[string]interface{}{"TIME": nil},
+			wantErr: true,
+		},
+		{
+			name:    "time malformed",
+			replies: map[string]interface{}{"TIME": []interface{}{[]byte("1600000000")}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeDo{replies: tt.replies, errs: tt.errs}
+			s := NewRedisStore(f.do, "throttle:")
+
+			v, got, err := s.GetWithTime(context.Background(), "k")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetWithTime() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if v != tt.want {
+				t.Errorf("GetWithTime() value = %d, want %d", v, tt.want)
+			}
+
+			if !got.Equal(now) {
+				t.Errorf("GetWithTime() time = %v, want %v", got, now)
+			}
+
+			if last := f.calls[len(f.calls)-1]; last.cmd != "GET" || last.args[0] != "throttle:k" {
+				t.Errorf("GetWithTime() call = %v, want GET throttle:k", last)
+			}
+		})
+	}
+}
+
+func TestSetIfNotExistsWithTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   interface{}
+		err     error
+		ttl     time.Duration
+		want    bool
+		wantTTL int
+		wantErr bool
+	}{
+		{name: "set", reply: "OK", ttl: 10 * time.Second, want: true, wantTTL: 10},
+		{name: "exists", reply: nil, ttl: 10 * time.Second, want: false, wantTTL: 10},
+		{name: "ttl at least one second", reply: []byte("OK"), ttl: time.Millisecond, want: true, wantTTL: 1},
+		{name: "error", err: errors.New("connection refused"), ttl: time.Second, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeDo{
+				replies: map[string]interface{}{"SET": tt.reply},
+				errs:    map[string]error{"SET": tt.err},
+			}
+			s := NewRedisStore(f.do, "throttle:")
+
+			ok, err := s.SetIfNotExistsWithTTL(context.Background(), "k", 7, tt.ttl)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetIfNotExistsWithTTL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if tt.wantErr {
+				return
+			}
+
+			if ok != tt.want {
+				t.Errorf("SetIfNotExistsWithTTL() = %v, want %v", ok, tt.want)
+			}
+
+			want := fmt.Sprint([]interface{}{"throttle:k", int64(7), "EX", tt.wantTTL, "NX"})
+			if got := fmt.Sprint(f.calls[0].args); got != want {
+				t.Errorf("SetIfNotExistsWithTTL() args = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCompareAndSwapWithTTL(t *testing.T) {
+	tests := []struct {
+		name    string
+		reply   interface{}
+		err     error
+		want    bool
+		wantErr bool
+	}{
+		{name: "swapped", reply: int64(1), want: true},
+		{name: "not swapped", reply: int64(0), want: false},
+		{name: "bytes reply", reply: []byte("1"), want: true},
+		{name: "nil reply", reply: nil, wantErr: true},
+		{name: "error value", reply: redisError("NOSCRIPT"), wantErr: true},
+		{name: "error", err: errors.New("connection refused"), wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &fakeDo{
+				replies: map[string]interface{}{"EVAL": tt.reply},
+				errs:    map[string]error{"EVAL": tt.err},
+			}
+			s := NewRedisStore(f.do, "throttle:")
+
+			ok, err := s.CompareAndSwapWithTTL(context.Background(), "k", 1, 2, 1500*time.Millisecond)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("CompareAndSwapWithTTL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if ok != tt.want {
+				t.Errorf("CompareAndSwapWithTTL() = %v, want %v", ok, tt.want)
+			}
+
+			want := fmt.Sprint([]interface{}{redisCASScript, 1, "throttle:k", int64(1), int64(2), 1})
+			if got := fmt.Sprint(f.calls[0].args); got != want {
+				t.Errorf("CompareAndSwapWithTTL() args = %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+func TestCanceledContext(t *testing.T) {
+	f := &fakeDo{}
+	s := NewRedisStore(f.do, "")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, _, err := s.GetWithTime(ctx, "k"); err == nil {
+		t.Error("GetWithTime() error = nil, want canceled")
+	}
+
+	if _, err := s.SetIfNotExistsWithTTL(ctx, "k", 1, time.Second); err == nil {
+		t.Error("SetIfNotExistsWithTTL() error = nil, want canceled")
+	}
+
+	if _, err := s.CompareAndSwapWithTTL(ctx, "k", 1, 2, time.Second); err == nil {
+		t.Error("CompareAndSwapWithTTL() error = nil, want canceled")
+	}
+
+	if len(f.calls) != 0 {
+		t.Errorf("calls = %v, want none", f.calls)
+	}
+}
+
+// memoryRedis 在内存中模拟 TIME、GET、SET NX 与 CAS 脚本，不处理过期
+type memoryRedis struct {
+	now    time.Time
+	values map[string]string
+}
+
+func (m *memoryRedis) do(cmd string, args ...interface{}) (interface{}, error) {
+	switch cmd {
+	case "TIME":
+		return timeReply(m.now), nil
+	case "GET":
+		v, ok := m.values[args[0].(string)]
+		if !ok {
+			return nil, nil
+		}
+		return []byte(v), nil
+	case "SET":
+		key := args[0].(string)
+		if _, ok := m.values[key]; ok {
+			return nil, nil
+		}
+		m.values[key] = fmt.Sprint(args[1])
+		return "OK", nil
+	case "EVAL":
+		key := args[2].(string)
+		v, ok := m.values[key]
+		if !ok || v != fmt.Sprint(args[3]) {
+			return int64(0), nil
+		}
+		m.values[key] = fmt.Sprint(args[4])
+		return int64(1), nil
+	}
+
+	return nil, fmt.Errorf("unknown command: %s", cmd)
+}
+
+func TestRedisStoreRateLimit(t *testing.T) {
+	m := &memoryRedis{now: time.Unix(1600000000, 0), values: map[string]string{}}
+
+	limiter, err := throttled.NewGCRARateLimiterCtx(NewRedisStore(m.do, "throttle:"), throttled.RateQuota{
+		MaxRate:  throttled.PerSec(1),
+		MaxBurst: 1,
+	})
+	if err != nil {
+		t.Fatalf("NewGCRARateLimiterCtx() error = %v", err)
+	}
+
+	want := []bool{false, false, true}
+	for i, w := range want {
+		limited, _, err := limiter.RateLimitCtx(context.Background(), "k", 1)
+		if err != nil {
+			t.Fatalf("RateLimitCtx() error = %v", err)
+		}
+
+		if limited != w {
+			t.Errorf("request %d limited = %v, want %v", i, limited, w)
+		}
+	}
+
+	// 服务器时间前进后恢复
+	m.now = m.now.Add(time.Second)
+	if limited, _, _ := limiter.RateLimitCtx(context.Background(), "k", 1); limited {
+		t.Error("limited after one second, want allowed")
+	}
+
+	if _, ok := m.values["throttle:k"]; !ok {
+		t.Errorf("values = %v, want key with prefix", m.values)
+	}
+}
@@ -2,13 +2,16 @@
 package throttle
 
 import (
-	"math"
 	"net/http"
 	"strconv"
 
 	throttled "github.com/throttled/throttled/v2"
 	memstore "github.com/throttled/throttled/v2/store/memstore"
 	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/ratelimit"
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // VaryBy 为请求生成唯一值
@@ -16,6 +19,11 @@ type VaryBy interface {
 	Key(*http.Request) string
 }
 
+// ContextVaryBy 基于 ctx 为请求生成唯一值，VaryBy 实现了该接口时优先使用
+type ContextVaryBy interface {
+	KeyContext(ctx zeroapi.Context) string
+}
+
 // Config 配置
 type Config struct {
 	// LimitHandler 发生限制时调用
@@ -30,8 +38,22 @@ type Config struct {
 	// Burst 每一个请求每分钟允许额外请求的个数，> 0
 	Burst int
 
+	// Rules 路由规则，按顺序匹配，使用第一个匹配的规则，均不匹配时使用 PerMin 与 Burst
+	Rules []*Rule
+
 	// VaryBy 为请求生成唯一值
 	VaryBy VaryBy
+
+	// Store 保存限流状态，默认为进程内的 memstore，多个副本时限制会成倍增加
+	// 使用 NewCacheStore 或 NewRedisStore 在多个副本之间共享限流状态
+	Store throttled.GCRAStoreCtx
+
+	// FailOpen Store 发生错误时是否放行请求，默认 false，调用 ErrHandler 拒绝请求
+	FailOpen bool
+
+	// Header 设置限流响应头，默认为 X-RateLimit-* 格式
+	// 可选择 IETF 的 RateLimit 与 RateLimit-Policy，或者不设置
+	Header *ratelimit.Writer
 }
 
 // New ..
@@ -61,38 +83,70 @@ func New(c *Config) zeroapi.Handler {
 		c.VaryBy = DefaultVaryBy()
 	}
 
-	store, err := memstore.New(65536)
-	if err != nil {
-		return nil
+	if c.Header == nil {
+		c.Header = &ratelimit.Writer{Style: ratelimit.StyleLegacy}
 	}
 
-	quota := throttled.RateQuota{
-		MaxRate:  throttled.PerMin(c.PerMin),
-		MaxBurst: c.Burst,
+	if c.Store == nil {
+		store, err := memstore.NewCtx(65536)
+		if err != nil {
+			return nil
+		}
+		c.Store = store
 	}
 
-	rateLimiter, err := throttled.NewGCRARateLimiter(store, quota)
+	fallback, err := newPolicy(c.Store, nil, "", []Quota{PerMin(c.PerMin, c.Burst)})
 	if err != nil {
 		return nil
 	}
 
+	policies := make([]*policy, 0, len(c.Rules))
+	for i, rule := range c.Rules {
+		if len(rule.Quotas) == 0 {
+			return nil
+		}
+
+		rule.compile()
+
+		p, err := newPolicy(c.Store, rule, "r"+strconv.Itoa(i), rule.Quotas)
+		if err != nil {
+			return nil
+		}
+		policies = append(policies, p)
+	}
+
 	return func(ctx zeroapi.Context) {
 		if ctx.Method() == http.MethodOptions {
 			return
 		}
 
 		var key string
-		if c.VaryBy != nil {
+		if v, ok := c.VaryBy.(ContextVaryBy); ok {
+			key = v.KeyContext(ctx)
+		} else if c.VaryBy != nil {
 			key = c.VaryBy.Key(ctx.Request())
 		}
 
-		limited, context, err := rateLimiter.RateLimit(key, 1)
+		p := fallback
+		for _, policy := range policies {
+			if policy.rule.match(ctx) {
+				p = policy
+				break
+			}
+		}
+
+		limited, result, err := p.rateLimit(ctx.Request().Context(), key)
 		if err != nil {
+			if c.FailOpen {
+				ctx.App().Logger().Errorf("throttle store failed, fail open, err: %s, method: %s, path: %s, ip: %s, request_id: %s", err.Error(), ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
+				return
+			}
+
 			c.ErrHandler(ctx, err)
 			return
 		}
 
-		setRateLimitHeaders(ctx, context)
+		c.Header.Write(ctx, result)
 
 		if limited {
 			c.LimitHandler(ctx)
@@ -105,7 +159,7 @@ func New(c *Config) zeroapi.Handler {
 func DefaultLimitHandler(ctx zeroapi.Context) {
 	ctx.SetHTTPCode(http.StatusTooManyRequests)
 	if _, err := ctx.Message(http.StatusTooManyRequests, "too many requests"); err != nil {
-		ctx.App().Logger().Errorf("set message failed, err: %s", err.Error())
+		ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 	}
 	ctx.Stopped()
 }
@@ -114,41 +168,30 @@ func DefaultLimitHandler(ctx zeroapi.Context) {
 func DefaultErrHandler(ctx zeroapi.Context, err error) {
 	ctx.SetHTTPCode(http.StatusInternalServerError)
 	if _, err := ctx.Message(http.StatusInternalServerError, "internal server error"); err != nil {
-		ctx.App().Logger().Errorf("set message failed, err: %s", err.Error())
+		ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
 	}
 	ctx.Stopped()
 }
 
-// DefaultVaryBy 默认的 key 生成器
+// DefaultVaryBy 默认的 key 生成器，使用客户端 ip、请求方法与路径
+//
+// 客户端 ip 由 realip 中间件解析，未使用 realip 时为 ctx.IP()
 func DefaultVaryBy() VaryBy {
-	return &throttled.VaryBy{
-		Separator:  "_",
-		RemoteAddr: true,
-		Method:     true,
-		Path:       true,
+	return &defaultVaryBy{
+		VaryBy: throttled.VaryBy{
+			Separator:  "_",
+			RemoteAddr: true,
+			Method:     true,
+			Path:       true,
+		},
 	}
 }
 
-func setRateLimitHeaders(ctx zeroapi.Context, context throttled.RateLimitResult) {
-	if v := context.Limit; v >= 0 {
-		// 最大访问次数
-		ctx.SetHeader("X-RateLimit-Limit", strconv.Itoa(v))
-	}
-
-	if v := context.Remaining; v >= 0 {
-		// 剩余访问次数
-		ctx.SetHeader("X-RateLimit-Remaining", strconv.Itoa(v))
-	}
-
-	if v := context.ResetAfter; v >= 0 {
-		vi := int(math.Ceil(v.Seconds()))
-		// 时间过后，访问次数重置
-		ctx.SetHeader("X-RateLimit-Reset", strconv.Itoa(vi))
-	}
+type defaultVaryBy struct {
+	throttled.VaryBy
+}
 
-	if v := context.RetryAfter; v >= 0 {
-		vi := int(math.Ceil(v.Seconds()))
-		// 需要等待多长时间之后才能继续发送请求
-		ctx.SetHeader("Retry-After", strconv.Itoa(vi))
-	}
+// KeyContext 使用 realip 解析的客户端 ip 代替 RemoteAddr
+func (v *defaultVaryBy) KeyContext(ctx zeroapi.Context) string {
+	return realip.IP(ctx) + v.Separator + ctx.Method() + v.Separator + ctx.Path()
 }
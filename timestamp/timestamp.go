@@ -7,6 +7,9 @@ import (
 	zeroapi "github.com/zerogo-hub/zero-api"
 
 	zerotime "github.com/zerogo-hub/zero-helper/time"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
 )
 
 // New 验证请求中的时间戳与服务端相比，是否相差太大
@@ -29,7 +32,7 @@ func New(opts ...Option) zeroapi.Handler {
 			if err != nil {
 				ctx.Stopped()
 				ctx.SetHTTPCode(http.StatusBadRequest)
-				ctx.App().Logger().Errorf("no valid timestamp: %d, method: %s, path: %s, ip: %s", timestamp, ctx.Method(), ctx.Path(), ctx.IP())
+				ctx.App().Logger().Errorf("no valid timestamp: %d, method: %s, path: %s, ip: %s, request_id: %s", timestamp, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 				return
 			}
 
@@ -38,7 +41,7 @@ func New(opts ...Option) zeroapi.Handler {
 			if timestamp > now+opt.Diff || timestamp < now-opt.Diff {
 				ctx.Stopped()
 				ctx.SetHTTPCode(http.StatusBadRequest)
-				ctx.App().Logger().Errorf("invalid timestamp: %d, method: %s, path: %s, ip: %s", timestamp, ctx.Method(), ctx.Path(), ctx.IP())
+				ctx.App().Logger().Errorf("invalid timestamp: %d, method: %s, path: %s, ip: %s, request_id: %s", timestamp, ctx.Method(), ctx.Path(), realip.IP(ctx), requestid.Get(ctx))
 				return
 			}
 		}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"os"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+	zamwatchdog "github.com/zerogo-hub/zero-api-middleware/watchdog"
+	app "github.com/zerogo-hub/zero-api/app"
+)
+
+func helloworldHandle(ctx zeroapi.Context) {
+	pid := os.Getpid()
+	ctx.Textf("`ctrl+c` to close, `kill %d` to shutdown, `kill -USR2 %d` to restart", pid, pid)
+}
+
+func hellohang(ctx zeroapi.Context) {
+	time.Sleep(time.Minute)
+	ctx.Text("done")
+}
+
+func main() {
+	a := app.New()
+
+	w := zamwatchdog.New(&zamwatchdog.Config{
+		Threshold: 3 * time.Second,
+	})
+	defer w.Stop()
+
+	a.Use(w.Handle)
+
+	a.Get("/", helloworldHandle)
+
+	// 3 秒后打印: slow request, elapsed: 3.000s, method: GET, path: /hang, ... 以及调用栈
+	a.Get("/hang", hellohang)
+
+	// curl http://127.0.0.1:8877/admin/inflight?min=1s
+	// [{"id":2,"method":"GET","path":"/hang","ip":"127.0.0.1","start":"...","age":5001234567,"goroutine":35,"slow":true}]
+	a.Get("/admin/inflight", w.AdminHandler())
+
+	// 监听信号，比如优雅关闭
+	a.Server().HTTPServer().ListenSignal()
+
+	if err := a.Run("127.0.0.1:8877"); err != nil {
+		a.Logger().Errorf("app run failed, err: %s", err.Error())
+	}
+}
@@ -0,0 +1,281 @@
+// Package watchdog 慢请求检测，在请求仍在处理时发现耗时过长的请求
+//
+// logger 在请求结束后才输出耗时，卡住的请求不会出现在日志中
+// watchdog 记录正在处理的请求，定时检查，耗时超过阈值时打印请求信息与处理该请求的 goroutine 的调用栈
+package watchdog
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	zeroapi "github.com/zerogo-hub/zero-api"
+
+	"github.com/zerogo-hub/zero-api-middleware/realip"
+	"github.com/zerogo-hub/zero-api-middleware/requestid"
+)
+
+// Config 配置
+type Config struct {
+	// Threshold 慢请求的阈值，默认 5 秒
+	Threshold time.Duration
+
+	// Interval 检查的间隔，默认 1 秒
+	Interval time.Duration
+
+	// DisableStack 是否不获取调用栈，获取调用栈需要短暂停止所有 goroutine
+	DisableStack bool
+
+	// OnSlow 发现慢请求时调用，每一个请求只调用一次，默认使用 app 的日志打印
+	OnSlow func(r *Request)
+}
+
+// Request 正在处理的请求
+type Request struct {
+	ID        uint64        `json:"id"`
+	RequestID string        `json:"request_id,omitempty"`
+	Method    string        `json:"method"`
+	Path      string        `json:"path"`
+	IP        string        `json:"ip"`
+	Start     time.Time     `json:"start"`
+	Age       time.Duration `json:"age"`
+	Goroutine uint64        `json:"goroutine"`
+	Slow      bool          `json:"slow"`
+
+	// Stack 处理该请求的 goroutine 的调用栈，只在 OnSlow 中有值
+	Stack string `json:"stack,omitempty"`
+
+	app zeroapi.App
+}
+
+// Watchdog 慢请求检测
+type Watchdog struct {
+	c *Config
+
+	lock     *sync.Mutex
+	inflight map[uint64]*Request
+	next     atomic.Uint64
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// New 慢请求检测，并启动后台检查
+func New(config *Config) *Watchdog {
+	c := &Config{}
+	if config != nil {
+		*c = *config
+	}
+
+	if c.Threshold <= 0 {
+		c.Threshold = 5 * time.Second
+	}
+
+	if c.Interval <= 0 {
+		c.Interval = time.Second
+	}
+
+	if c.OnSlow == nil {
+		c.OnSlow = logSlow
+	}
+
+	w := &Watchdog{
+		c:        c,
+		lock:     &sync.Mutex{},
+		inflight: make(map[uint64]*Request),
+		stop:     make(chan struct{}),
+	}
+
+	go w.watch()
+
+	return w
+}
+
+// Handle 中间件，记录正在处理的请求，应放在其它中间件之前
+func (w *Watchdog) Handle(ctx zeroapi.Context) {
+	r := &Request{
+		ID:        w.next.Add(1),
+		RequestID: requestid.Get(ctx),
+		Method:    ctx.Method(),
+		Path:      ctx.Path(),
+		IP:        realip.IP(ctx),
+		Start:     time.Now(),
+		Goroutine: goroutineID(),
+		app:       ctx.App(),
+	}
+
+	w.lock.Lock()
+	w.inflight[r.ID] = r
+	w.lock.Unlock()
+
+	ctx.AppendEnd(func() error {
+		w.lock.Lock()
+		delete(w.inflight, r.ID)
+		w.lock.Unlock()
+		return nil
+	})
+}
+
+// InFlight 正在处理的请求，按耗时从长到短排序
+func (w *Watchdog) InFlight() []Request {
+	now := time.Now()
+
+	w.lock.Lock()
+	requests := make([]Request, 0, len(w.inflight))
+	for _, r := range w.inflight {
+		request := *r
+		request.Age = now.Sub(r.Start)
+		requests = append(requests, request)
+	}
+	w.lock.Unlock()
+
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Age > requests[j].Age })
+
+	return requests
+}
+
+// AdminHandler 返回正在处理的请求，需自行注册到受保护的路由上
+//
+// 参数 min(如 "1s") 只返回耗时超过该值的请求
+func (w *Watchdog) AdminHandler() zeroapi.Handler {
+	return func(ctx zeroapi.Context) {
+		var minAge time.Duration
+		if s := ctx.Query("min"); len(s) > 0 {
+			d, err := time.ParseDuration(s)
+			if err != nil || d < 0 {
+				ctx.SetHTTPCode(http.StatusBadRequest)
+				if _, err := ctx.Message(http.StatusBadRequest, "invalid min"); err != nil {
+					ctx.App().Logger().Errorf("set message failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+				}
+				return
+			}
+			minAge = d
+		}
+
+		requests := w.InFlight()
+		for i, r := range requests {
+			if r.Age < minAge {
+				requests = requests[:i]
+				break
+			}
+		}
+
+		b, err := json.Marshal(requests)
+		if err != nil {
+			ctx.SetHTTPCode(http.StatusInternalServerError)
+			ctx.App().Logger().Errorf("marshal inflight requests failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+			return
+		}
+
+		if _, err := ctx.Text(string(b)); err != nil {
+			ctx.App().Logger().Errorf("write inflight requests failed, err: %s, request_id: %s", err.Error(), requestid.Get(ctx))
+		}
+	}
+}
+
+// Stop 停止后台检查，可重复调用
+func (w *Watchdog) Stop() {
+	w.stopOnce.Do(func() {
+		close(w.stop)
+	})
+}
+
+func (w *Watchdog) watch() {
+	ticker := time.NewTicker(w.c.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			w.check()
+		case <-w.stop:
+			return
+		}
+	}
+}
+
+// check 找出新的慢请求，每一个请求只报告一次
+func (w *Watchdog) check() {
+	now := time.Now()
+
+	var slow []Request
+
+	w.lock.Lock()
+	for _, r := range w.inflight {
+		if r.Slow || now.Sub(r.Start) < w.c.Threshold {
+			continue
+		}
+
+		r.Slow = true
+		request := *r
+		request.Age = now.Sub(r.Start)
+		slow = append(slow, request)
+	}
+	w.lock.Unlock()
+
+	if len(slow) == 0 {
+		return
+	}
+
+	var stacks map[uint64]string
+	if !w.c.DisableStack {
+		stacks = goroutineStacks()
+	}
+
+	for i := range slow {
+		slow[i].Stack = stacks[slow[i].Goroutine]
+		w.c.OnSlow(&slow[i])
+	}
+}
+
+// logSlow 默认使用 app 的日志打印慢请求
+func logSlow(r *Request) {
+	r.app.Logger().Warnf("slow request, elapsed: %s, method: %s, path: %s, ip: %s, request_id: %s\n%s", r.Age, r.Method, r.Path, r.IP, r.RequestID, r.Stack)
+}
+
+// goroutineID 当前 goroutine 的 id，解析调用栈的第一行 "goroutine 123 [running]:"
+func goroutineID() uint64 {
+	var buf [64]byte
+	b := buf[:runtime.Stack(buf[:], false)]
+	b = bytes.TrimPrefix(b, []byte("goroutine "))
+	if i := bytes.IndexByte(b, ' '); i > 0 {
+		id, _ := strconv.ParseUint(string(b[:i]), 10, 64)
+		return id
+	}
+
+	return 0
+}
+
+// goroutineStacks 所有 goroutine 的调用栈，key 为 goroutine id
+func goroutineStacks() map[uint64]string {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		if len(buf) >= 64<<20 {
+			break
+		}
+		buf = make([]byte, len(buf)*2)
+	}
+
+	stacks := make(map[uint64]string)
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		b := bytes.TrimPrefix(stack, []byte("goroutine "))
+		if i := bytes.IndexByte(b, ' '); i > 0 {
+			if id, err := strconv.ParseUint(string(b[:i]), 10, 64); err == nil {
+				stacks[id] = string(stack)
+			}
+		}
+	}
+
+	return stacks
+}